@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/audit"
+)
+
+// ListAudit
+// @Summary 查询审计日志
+// @Produce  json
+// @Param page query int true "Page"
+// @Param pageSize query int true "PageSize"
+// @Param appKey query string false "AppKey"
+// @Param cluster query string false "Cluster"
+// @Param namespace query string false "Namespace"
+// @Param resource query string false "Resource"
+// @Param verb query string false "Verb"
+// @Param event query string false "request, session_start or session_end"
+// @Param sessionID query string false "SessionID"
+// @Param failed query bool false "Failed"
+// @Param since query string false "RFC3339"
+// @Param until query string false "RFC3339"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /admin/audit [get]
+func ListAudit(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var q audit.ListQuery
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	res, total, err := audit.List(q)
+	if err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.SuccessExtra(total, q.Page, q.PageSize, http.StatusOK, "ok", res)
+}
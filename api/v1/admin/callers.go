@@ -0,0 +1,168 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/caller"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+)
+
+type RotateCallerQuery struct {
+	GracePeriod *int `form:"gracePeriod" binding:"omitempty,gte=0"` // 秒，旧secret的有效期
+}
+
+// callerFailStatus is dbFailStatus, hmac callers without a master key to
+// seal their secret are a bad request
+func callerFailStatus(err error) int {
+	if err == models.ErrNoCryptoKey {
+		return http.StatusBadRequest
+	}
+	return dbFailStatus(err)
+}
+
+// ListCallers
+// @Summary 查看调用方
+// @Produce  json
+// @Param page query int true "Page"
+// @Param pageSize query int true "PageSize"
+// @Success 200 {object} app.Response
+// @Router /admin/callers [get]
+func ListCallers(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var pageInfo app.PageInfo
+	if err := appG.C.ShouldBindQuery(&pageInfo); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	res, total, err := caller.List(pageInfo)
+	if err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.SuccessExtra(total, pageInfo.Page, pageInfo.PageSize, http.StatusOK, "ok", res)
+}
+
+// GetCaller
+// @Summary 查看调用方详情
+// @Produce  json
+// @Param id path int true "ID"
+// @Success 200 {object} app.Response
+// @Router /admin/callers/{id} [get]
+func GetCaller(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var u app.GetById
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	res, err := caller.Get(u.ID)
+	if err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", res)
+}
+
+// PostCaller
+// @Summary 新增调用方，secret仅在响应中返回一次，hmac模式需要配置crypto.keys
+// @accept application/json
+// @Param RequestBody body caller.CallerBody true "RequestBody"
+// @Success 200 {object} app.Response
+// @Router /admin/callers [post]
+func PostCaller(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var b caller.CallerBody
+	if err := appG.C.ShouldBindJSON(&b); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	res, err := caller.Create(b)
+	if err != nil {
+		appG.Fail(callerFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Created Successfully", res)
+}
+
+// PutCaller
+// @Summary 更新调用方，未传enabled时保持原值，appKey及mode不可修改
+// @accept application/json
+// @Param id path int true "ID"
+// @Param RequestBody body caller.CallerBody true "RequestBody"
+// @Success 200 {object} app.Response
+// @Router /admin/callers/{id} [put]
+func PutCaller(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u app.GetById
+		b caller.CallerBody
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindJSON(&b); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := caller.Update(u.ID, b); err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Updated Successfully", nil)
+}
+
+// RotateCaller
+// @Summary 轮换调用方secret，旧secret在宽限期内仍然有效
+// @Param id path int true "ID"
+// @Param gracePeriod query int false "GracePeriod"
+// @Success 200 {object} app.Response
+// @Router /admin/callers/{id}/rotate [post]
+func RotateCaller(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u app.GetById
+		q RotateCallerQuery
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	gracePeriod := config.RotateGracePeriod()
+	if q.GracePeriod != nil {
+		gracePeriod = time.Duration(*q.GracePeriod) * time.Second
+	}
+	res, err := caller.Rotate(u.ID, gracePeriod)
+	if err != nil {
+		appG.Fail(callerFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Rotated Successfully", res)
+}
+
+// DeleteCaller
+// @Summary 删除调用方
+// @Param id path int true "ID"
+// @Success 200 {object} app.Response
+// @Router /admin/callers/{id} [delete]
+func DeleteCaller(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var u app.GetById
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := caller.Delete(u.ID); err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Deleted Successfully", nil)
+}
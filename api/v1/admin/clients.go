@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+)
+
+type ClientUri struct {
+	Cluster string `uri:"cluster" binding:"required"`
+}
+
+// ListClients
+// @Summary 查看已缓存的集群客户端
+// @Produce  json
+// @Success 200 {object} app.Response
+// @Router /admin/clients [get]
+func ListClients(c *gin.Context) {
+	appG := app.Gin{C: c}
+	appG.Success(http.StatusOK, "ok", k8s.ListClients())
+}
+
+// RefreshClient
+// @Summary 重建集群客户端缓存
+// @Produce  json
+// @Param cluster path string true "Cluster"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /admin/clients/{cluster}/refresh [post]
+func RefreshClient(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var u ClientUri
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	client, err := k8s.RefreshClient(u.Cluster)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Refreshed Successfully", k8s.ClientInfo{
+		Cluster: u.Cluster,
+		Host:    client.RestConfig.Host,
+		BuiltAt: client.BuiltAt,
+	})
+}
+
+// DeleteClient
+// @Summary 清除集群客户端缓存
+// @Produce  json
+// @Param cluster path string true "Cluster"
+// @Success 200 {object} app.Response
+// @Router /admin/clients/{cluster} [delete]
+func DeleteClient(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var u ClientUri
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8s.RemoveClient(u.Cluster)
+	appG.Success(http.StatusOK, "Deleted Successfully", nil)
+}
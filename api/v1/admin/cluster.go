@@ -1,14 +1,31 @@
 package v1
 
 import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/cluster"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
 	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 )
 
+// storeFailStatus rejects writes to a read-only storage backend with 405
+func storeFailStatus(err error) int {
+	if err == models.ErrReadOnly {
+		return http.StatusMethodNotAllowed
+	}
+	if errors.Is(err, models.ErrDuplicateName) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 func PostCluster(c *gin.Context) {
 	appG := app.Gin{C: c}
 	var (
@@ -19,7 +36,7 @@ func PostCluster(c *gin.Context) {
 		return
 	}
 	if err := cluster.Create(b); err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(storeFailStatus(err), err, nil)
 		return
 	}
 	appG.Success(http.StatusOK, "Created Successfully", nil)
@@ -40,7 +57,40 @@ func PutCluster(c *gin.Context) {
 		return
 	}
 	if err := cluster.Update(u.ID, b); err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		if err == cluster.ErrRedactedContext {
+			appG.Fail(http.StatusBadRequest, err, nil)
+			return
+		}
+		appG.Fail(storeFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Updated Successfully", nil)
+}
+
+// PutClusterOptions
+// @Summary 更新集群客户端参数
+// @accept application/json
+// @Param id path int true "ID"
+// @Param RequestBody body models.ClientOptions true "RequestBody"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /admin/clusters/{id}/options [put]
+func PutClusterOptions(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u app.GetById
+		b models.ClientOptions
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindJSON(&b); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := cluster.UpdateOptions(u.ID, b); err != nil {
+		appG.Fail(storeFailStatus(err), err, nil)
 		return
 	}
 	appG.Success(http.StatusOK, "Updated Successfully", nil)
@@ -65,6 +115,12 @@ func ListCluster(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	for _, item := range res {
+		if err := cluster.Redact(item); err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	appG.SuccessExtra(count, pageInfo.Page, pageInfo.PageSize, http.StatusOK, "ok", res)
 }
 
@@ -83,6 +139,10 @@ func GetCluster(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if err := cluster.Redact(&res); err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", res)
 }
 
@@ -97,13 +157,120 @@ func DeleteCluster(c *gin.Context) {
 	}
 
 	if err := cluster.Delete(idInfo.ID); err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(storeFailStatus(err), err, nil)
 		return
 	}
 
 	appG.Success(http.StatusOK, "Deleted Successfully", nil)
 }
 
-func TestConnectCluster(c *gin.Context) {
+type ImportClusterQuery struct {
+	Contexts string `form:"contexts"` // 逗号分隔，为空时导入全部context
+	Desc     string `form:"desc"`
+}
+
+// ImportCluster
+// @Summary 从kubeconfig导入集群
+// @accept multipart/form-data,application/yaml
+// @Param contexts query string false "Contexts"
+// @Param desc query string false "Desc"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /admin/clusters/import [post]
+func ImportCluster(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		q          ImportClusterQuery
+		kubeConfig []byte
+		selected   []string
+	)
+	// the body is the kubeconfig, only the query carries options
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	file, err := appG.C.FormFile("kubeconfig")
+	switch err {
+	case nil:
+		f, err := file.Open()
+		if err != nil {
+			appG.Fail(http.StatusBadRequest, err, nil)
+			return
+		}
+		defer f.Close()
+		if kubeConfig, err = ioutil.ReadAll(f); err != nil {
+			appG.Fail(http.StatusBadRequest, err, nil)
+			return
+		}
+	case http.ErrNotMultipart, http.ErrMissingFile:
+		if kubeConfig, err = ioutil.ReadAll(appG.C.Request.Body); err != nil {
+			appG.Fail(http.StatusBadRequest, err, nil)
+			return
+		}
+	default:
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if len(kubeConfig) == 0 {
+		appG.Fail(http.StatusBadRequest, errors.New("kubeconfig is empty"), nil)
+		return
+	}
+	for _, context := range strings.Split(q.Contexts, ",") {
+		if context = strings.TrimSpace(context); context != "" {
+			selected = append(selected, context)
+		}
+	}
+	results, err := cluster.Import(kubeConfig, selected, q.Desc)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", results)
+}
+
+// ReencryptClusters seals all stored kubeconfigs with the current master key
+func ReencryptClusters(c *gin.Context) {
+	appG := app.Gin{C: c}
+	count, err := cluster.Reencrypt()
+	if err != nil {
+		appG.Fail(storeFailStatus(err), err, map[string]int{"reencrypted": count})
+		return
+	}
+	appG.Success(http.StatusOK, "Reencrypted Successfully", map[string]int{"reencrypted": count})
+}
+
+type TestConnectQuery struct {
+	Namespace string `form:"namespace"`
+}
 
+// TestConnectCluster probes the submitted cluster without persisting it
+func TestConnectCluster(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		q       TestConnectQuery
+		b       models.ClusterModel
+		context clientcmdapiv1.Config
+	)
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindJSON(&b); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if q.Namespace == "" {
+		q.Namespace = "default"
+	}
+	if err := json.Unmarshal(b.Context, &context); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	_, restConf, err := k8s.BuildClient(context, b.ClientOptions)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, &k8s.ClusterProbe{ErrorType: k8s.ProbeErrorConfig, Error: err.Error()})
+		return
+	}
+	probe := k8s.ProbeCluster(appG.C.Request.Context(), restConf, q.Namespace)
+	appG.Success(http.StatusOK, "ok", probe)
 }
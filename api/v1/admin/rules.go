@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/rule"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	"gorm.io/gorm"
+)
+
+// dbFailStatus maps errors of database only features to http status codes
+func dbFailStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case err == models.ErrNoDatabase:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListRules
+// @Summary 查看调用方授权规则
+// @Produce  json
+// @Param page query int true "Page"
+// @Param pageSize query int true "PageSize"
+// @Param appKey query string false "AppKey"
+// @Success 200 {object} app.Response
+// @Router /admin/rules [get]
+func ListRules(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var q rule.ListQuery
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	res, total, err := rule.List(q)
+	if err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.SuccessExtra(total, q.Page, q.PageSize, http.StatusOK, "ok", res)
+}
+
+// GetRule
+// @Summary 查看调用方授权规则详情
+// @Produce  json
+// @Param id path int true "ID"
+// @Success 200 {object} app.Response
+// @Router /admin/rules/{id} [get]
+func GetRule(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var u app.GetById
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	res, err := rule.Get(u.ID)
+	if err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", res)
+}
+
+// PostRule
+// @Summary 新增调用方授权规则
+// @accept application/json
+// @Param RequestBody body models.Rule true "RequestBody"
+// @Success 200 {object} app.Response
+// @Router /admin/rules [post]
+func PostRule(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var b models.RuleModel
+	if err := appG.C.ShouldBindJSON(&b); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	res, err := rule.Create(b)
+	if err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Created Successfully", res)
+}
+
+// PutRule
+// @Summary 更新调用方授权规则
+// @accept application/json
+// @Param id path int true "ID"
+// @Param RequestBody body models.Rule true "RequestBody"
+// @Success 200 {object} app.Response
+// @Router /admin/rules/{id} [put]
+func PutRule(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u app.GetById
+		b models.RuleModel
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindJSON(&b); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := rule.Update(u.ID, b); err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Updated Successfully", nil)
+}
+
+// DeleteRule
+// @Summary 删除调用方授权规则
+// @Param id path int true "ID"
+// @Success 200 {object} app.Response
+// @Router /admin/rules/{id} [delete]
+func DeleteRule(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var u app.GetById
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := rule.Delete(u.ID); err != nil {
+		appG.Fail(dbFailStatus(err), err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Deleted Successfully", nil)
+}
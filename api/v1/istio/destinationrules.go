@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	networkingv1alpha3 "istio.io/api/networking/v1alpha3"
 	"istio.io/client-go/pkg/apis/networking/v1alpha3"
 	versionedclient "istio.io/client-go/pkg/clientset/versioned"
@@ -14,7 +15,7 @@ import (
 )
 
 type DestinationRulesQuery struct {
-	Namespace string `form:"namespace"`
+	metadata.NamespaceListParameter
 }
 
 type DestinationRulesUri struct {
@@ -46,20 +47,24 @@ func GetDestinationRules(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	k8sclient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
 	istioclient := versionedclient.NewForConfigOrDie(k8sclient.RestConfig)
-	drList, err := istioclient.NetworkingV1alpha3().DestinationRules(q.Namespace).List(context.TODO(), metav1.ListOptions{})
+	drList, err := istioclient.NetworkingV1alpha3().DestinationRules(q.ListNamespace()).List(context.TODO(), q.ListOptions())
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	appG.Success(http.StatusOK, "ok", drList)
+	if q.Output != "" {
+		appG.SuccessTable(drList, q.Limit, q.Output)
+		return
+	}
+	appG.SuccessList(drList, q.Limit, drList)
 }
 
 func GetDestinationRule(c *gin.Context) {
@@ -78,7 +83,7 @@ func GetDestinationRule(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	k8sclient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -114,7 +119,12 @@ func PostDestinationRule(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sclient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -126,6 +136,10 @@ func PostDestinationRule(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(nil, dr)
+		return
+	}
 
 	appG.Success(http.StatusOK, "ok", dr)
 }
@@ -152,7 +166,12 @@ func PutDestinationRule(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sclient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -165,12 +184,17 @@ func PutDestinationRule(c *gin.Context) {
 		return
 	}
 
+	live := dr.DeepCopy()
 	dr.Spec = b
-	_, err = istioclient.NetworkingV1alpha3().DestinationRules(u.Namespace).Update(context.TODO(), dr, metav1.UpdateOptions{})
+	result, err := istioclient.NetworkingV1alpha3().DestinationRules(u.Namespace).Update(context.TODO(), dr, metav1.UpdateOptions{})
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
 
 	appG.Success(http.StatusOK, "ok", dr)
 }
@@ -185,18 +209,35 @@ func DeleteDestinationRule(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sclient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 	istioclient := versionedclient.NewForConfigOrDie(k8sclient.RestConfig)
 
+	var live interface{}
+	if dryRun {
+		live, err = istioclient.NetworkingV1alpha3().DestinationRules(u.Namespace).Get(context.TODO(), u.DrName, metav1.GetOptions{})
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	err = istioclient.NetworkingV1alpha3().DestinationRules(u.Namespace).Delete(context.TODO(), u.DrName, metav1.DeleteOptions{})
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 
 	appG.Success(http.StatusOK, "ok", nil)
 }
@@ -6,7 +6,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/istio"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	networkingv1alpha3 "istio.io/api/networking/v1alpha3"
 	"istio.io/client-go/pkg/apis/networking/v1alpha3"
 	versionedclient "istio.io/client-go/pkg/clientset/versioned"
@@ -14,7 +16,7 @@ import (
 )
 
 type VirtualServicesQuery struct {
-	Namespace string `form:"namespace"`
+	metadata.NamespaceListParameter
 }
 
 type VirtualServicesUri struct {
@@ -45,21 +47,25 @@ func GetVirtualServices(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	k8sclient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
 	istioclient := versionedclient.NewForConfigOrDie(k8sclient.RestConfig)
-	vsList, err := istioclient.NetworkingV1alpha3().VirtualServices(q.Namespace).List(context.TODO(), metav1.ListOptions{})
+	vsList, err := istioclient.NetworkingV1alpha3().VirtualServices(q.ListNamespace()).List(context.TODO(), q.ListOptions())
 
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	appG.Success(http.StatusOK, "ok", vsList)
+	if q.Output != "" {
+		appG.SuccessTable(vsList, q.Limit, q.Output)
+		return
+	}
+	appG.SuccessList(vsList, q.Limit, vsList)
 }
 
 func GetVirtualService(c *gin.Context) {
@@ -77,7 +83,7 @@ func GetVirtualService(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	k8sclient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -113,7 +119,12 @@ func PostVirtualService(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sclient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -125,6 +136,10 @@ func PostVirtualService(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(nil, vs)
+		return
+	}
 
 	appG.Success(http.StatusOK, "ok", vs)
 }
@@ -150,22 +165,44 @@ func PutVirtualService(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	expected, err := app.ExpectedResourceVersion(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sclient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
 	istioclient := versionedclient.NewForConfigOrDie(k8sclient.RestConfig)
-	vs, err := istioclient.NetworkingV1alpha3().VirtualServices(u.Namespace).Get(context.TODO(), u.VSName, metav1.GetOptions{})
+	virtualServices := istioclient.NetworkingV1alpha3().VirtualServices(u.Namespace)
+	var live, result *v1alpha3.VirtualService
+	err = k8s.RetryOnConflict(expected, func() error {
+		vs, err := virtualServices.Get(context.TODO(), u.VSName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := k8s.CheckResourceVersion(vs, expected, istio.VirtualServiceResource); err != nil {
+			return err
+		}
+		live = vs.DeepCopy()
+		vs.Spec = b
+		result, err = virtualServices.Update(context.TODO(), vs, metav1.UpdateOptions{})
+		return err
+	})
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	vs.Spec = b
-	_, err = istioclient.NetworkingV1alpha3().VirtualServices(u.Namespace).Update(context.TODO(), vs, metav1.UpdateOptions{})
-	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+	if dryRun {
+		appG.SuccessDryRun(live, result)
 		return
 	}
 
@@ -182,18 +219,35 @@ func DeleteVirtualService(c *gin.Context) {
 		return
 	}
 
-	k8sclient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sclient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 	istioclient := versionedclient.NewForConfigOrDie(k8sclient.RestConfig)
 
+	var live interface{}
+	if dryRun {
+		live, err = istioclient.NetworkingV1alpha3().VirtualServices(u.Namespace).Get(context.TODO(), u.VSName, metav1.GetOptions{})
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	err = istioclient.NetworkingV1alpha3().VirtualServices(u.Namespace).Delete(context.TODO(), u.VSName, metav1.DeleteOptions{})
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 
 	appG.Success(http.StatusOK, "ok", nil)
 }
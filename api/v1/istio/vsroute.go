@@ -42,7 +42,7 @@ func GetVSHttpRoutes(c *gin.Context) {
 		return
 	}
 
-	istioclient, err := istio.NewIstioClient(u.Cluster)
+	istioclient, err := istio.NewIstioClient(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -64,7 +64,7 @@ func GetVSHttpRoute(c *gin.Context) {
 		return
 	}
 
-	istioclient, err := istio.NewIstioClient(u.Cluster)
+	istioclient, err := istio.NewIstioClient(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -98,17 +98,44 @@ func AddVSHttpRoute(c *gin.Context) {
 		return
 	}
 
-	istioclient, err := istio.NewIstioClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	expected, err := app.ExpectedResourceVersion(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	operation := istio.NewVSHttpRouteOperation(istioclient, u.Namespace)
+	istioclient, err := istio.NewIstioClientFor(k8sClient)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	operation := istio.NewVSHttpRouteOperation(istioclient, u.Namespace).Expect(expected)
+	var live interface{}
+	if dryRun {
+		live, err = operation.GetVS(context.TODO(), u.VSName)
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	result, err := operation.Create(context.TODO(), u.VSName, &b)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
 
@@ -127,17 +154,44 @@ func UpdateVSHttpRoute(c *gin.Context) {
 		return
 	}
 
-	istioclient, err := istio.NewIstioClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	expected, err := app.ExpectedResourceVersion(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	operation := istio.NewVSHttpRouteOperation(istioclient, u.Namespace)
+	istioclient, err := istio.NewIstioClientFor(k8sClient)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	operation := istio.NewVSHttpRouteOperation(istioclient, u.Namespace).Expect(expected)
+	var live interface{}
+	if dryRun {
+		live, err = operation.GetVS(context.TODO(), u.VSName)
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	result, err := operation.Update(context.TODO(), u.VSName, u.RouteName, &b)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
 
@@ -156,16 +210,43 @@ func DeleteVSHttpRoute(c *gin.Context) {
 		return
 	}
 
-	istioclient, err := istio.NewIstioClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	expected, err := app.ExpectedResourceVersion(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	operation := istio.NewVSHttpRouteOperation(istioclient, u.Namespace)
+	istioclient, err := istio.NewIstioClientFor(k8sClient)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	operation := istio.NewVSHttpRouteOperation(istioclient, u.Namespace).Expect(expected)
+	var live interface{}
+	if dryRun {
+		live, err = operation.GetVS(context.TODO(), u.VSName)
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	result, err := operation.Delete(context.TODO(), u.VSName, u.RouteName, &q)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+)
+
+type ApplyUri struct {
+	Cluster string `uri:"cluster" binding:"required"`
+}
+
+type ApplyQuery struct {
+	Namespace    string `form:"namespace"`    // 未设置metadata.namespace的对象使用，默认default
+	FieldManager string `form:"fieldManager"` // 默认使用配置apply.fieldManager
+	Force        bool   `form:"force"`        // 强制接管其他fieldManager的冲突字段
+	DryRun       bool   `form:"dryRun"`       // 只返回结果及diff，不写入
+}
+
+// PostApply
+// @Summary 以server-side apply方式应用多文档YAML或JSON，按顺序返回每个对象的结果
+// @accept application/yaml
+// @Param cluster path string true "Cluster"
+// @Param param query ApplyQuery false "ApplyQuery"
+// @Success 200 {object} app.Response
+// @Failure 422 {object} app.Response
+// @Router /k8s/{cluster}/apply [post]
+func PostApply(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u ApplyUri
+		q ApplyQuery
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	objs, err := k8s.DecodeManifests(c.Request.Body)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, q.DryRun)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if q.FieldManager == "" {
+		q.FieldManager = config.ApplyFieldManager()
+	}
+	applier := k8s.NewApplier(u.Cluster, k8sClient, k8s.ApplyOptions{
+		FieldManager: q.FieldManager,
+		Force:        q.Force,
+		Namespace:    q.Namespace,
+		DryRun:       q.DryRun,
+	})
+
+	identity := app.GetIdentity(c)
+	results := make([]k8s.ApplyResult, len(objs))
+	failed := 0
+	for i, obj := range objs {
+		result, diff, err := func() (string, []k8s.DiffEntry, error) {
+			mapping, err := applier.Prepare(obj)
+			if err != nil {
+				return k8s.ApplyError, nil, err
+			}
+			authorize := func(verb string) error {
+				return app.CheckAuthorized(identity, app.RequestAttributes{
+					Cluster:   u.Cluster,
+					Namespace: obj.GetNamespace(),
+					Resource:  k8s.ResourceName(mapping),
+					Verb:      verb,
+				})
+			}
+			// reading the live object tells whether it exists, which needs get
+			if err := authorize(app.VerbGet); err != nil {
+				return k8s.ApplyError, nil, err
+			}
+			live, err := applier.Live(context.TODO(), mapping, obj)
+			if err != nil {
+				return k8s.ApplyError, nil, err
+			}
+			// applying a missing object creates it
+			verb := app.VerbPatch
+			if live == nil {
+				verb = app.VerbCreate
+			}
+			if err := authorize(verb); err != nil {
+				return k8s.ApplyError, nil, err
+			}
+			return applier.Apply(context.TODO(), mapping, obj, live)
+		}()
+		results[i] = k8s.ApplyResult{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+			Result:     result,
+			Diff:       diff,
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			failed++
+		}
+	}
+	if failed > 0 {
+		appG.Fail(http.StatusUnprocessableEntity, fmt.Errorf("%d of %d objects failed to apply", failed, len(objs)), results)
+		return
+	}
+	if q.DryRun {
+		appG.Success(http.StatusOK, "dry run", results)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", results)
+}
@@ -14,6 +14,7 @@ import (
 // @Summary 创建Configmap资源
 // @accept application/json
 // @Param cluster path string true "Cluster"
+// @Param dryRun query bool false "DryRun"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/configmaps [post]
@@ -31,7 +32,12 @@ func PostConfigmap(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -43,6 +49,10 @@ func PostConfigmap(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(nil, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
 
@@ -50,8 +60,8 @@ func PostConfigmap(c *gin.Context) {
 // @Summary 获取Configmap资源列表
 // @accept application/json
 // @Param cluster path string true "Cluster"
-// @Param param query metadata.CommonQueryParameter true "LabelSelector"
-// @Success 200 {object} app.Response
+// @Param param query metadata.CommonQueryParameter true "namespace与allNamespaces二选一"
+// @Success 200 {object} app.ResponseExtra
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/configmaps [get]
 func GetConfigmapList(c *gin.Context) {
@@ -66,7 +76,7 @@ func GetConfigmapList(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(pathParam["cluster"])
+	k8sClient, err := k8s.GetClientAs(pathParam["cluster"], app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -78,7 +88,11 @@ func GetConfigmapList(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", result)
+	if queryParam.Output != "" {
+		appG.SuccessTable(result, queryParam.Limit, queryParam.Output)
+		return
+	}
+	appG.SuccessList(result, queryParam.Limit, result.Items)
 }
 
 // GetConfigmap
@@ -97,7 +111,7 @@ func GetConfigmap(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	k8sClient, err := k8s.GetClientAs(param["cluster"], app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -115,6 +129,7 @@ func GetConfigmap(c *gin.Context) {
 // @Summary 更新Configmap资源
 // @accept application/json
 // @Param cluster path string true "Cluster"
+// @Param dryRun query bool false "DryRun"
 // @Param namespace path string true "Namespace"
 // @Param name path string true "Name"
 // @Success 200 {object} app.Response
@@ -132,17 +147,92 @@ func PutConfigmap(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 	configMapOperation := k8s.NewConfigmapOperation(k8sClient.ClientV1)
+	var live interface{}
+	if dryRun {
+		live, err = configMapOperation.Get(context.TODO(), param["namespace"], param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	result, err := configMapOperation.Update(context.TODO(), param["namespace"], param["name"], &configMap)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", result)
+}
+
+// PatchConfigmap
+// @Summary 按Content-Type以json patch、merge patch、strategic merge patch或apply patch更新Configmap
+// @accept application/json-patch+json
+// @accept application/merge-patch+json
+// @accept application/strategic-merge-patch+json
+// @accept application/apply-patch+yaml
+// @Param cluster path string true "Cluster"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Name"
+// @Param fieldManager query string false "FieldManager"
+// @Param force query bool false "Force"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/configmaps/{namespace}/{name} [patch]
+func PatchConfigmap(c *gin.Context) {
+	appG := app.Gin{C: c}
+	param, err := app.GetPathParameterString(c, "cluster", "namespace", "name")
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	patchType, data, opts, status, err := patchRequest(c)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	configMapOperation := k8s.NewConfigmapOperation(k8sClient.ClientV1)
+	var live interface{}
+	if dryRun {
+		live, err = configMapOperation.Get(context.TODO(), param["namespace"], param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	result, err := configMapOperation.Patch(context.TODO(), param["namespace"], param["name"], patchType, data, opts)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
 
@@ -150,6 +240,7 @@ func PutConfigmap(c *gin.Context) {
 // @Summary 删除Configmap资源
 // @accept application/json
 // @Param cluster path string true "Cluster"
+// @Param dryRun query bool false "DryRun"
 // @Param namespace path string true "Namespace"
 // @Param name path string true "Name"
 // @Success 200 {object} app.Response
@@ -162,17 +253,34 @@ func DeleteConfigmap(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
 	configMapOperation := k8s.NewConfigmapOperation(k8sClient.ClientV1)
+	var live interface{}
+	if dryRun {
+		live, err = configMapOperation.Get(context.TODO(), param["namespace"], param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	err = configMapOperation.Delete(context.TODO(), param["namespace"], param["name"])
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", nil)
 }
@@ -9,10 +9,38 @@ import (
 	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	"io/ioutil"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
 	"net/http"
 )
 
+// crdOperation returns the operation and resource addressed by the path,
+// the namespace path param is empty on clustercrd routes. Writes are not
+// persisted when dryRun is set.
+func crdOperation(c *gin.Context, dryRun bool) (k8s.CRDInterface, schema.GroupVersionResource, int, error) {
+	param, err := app.GetPathParameterString(c, "cluster", "group", "version", "resource")
+	if err != nil {
+		return nil, schema.GroupVersionResource{}, http.StatusBadRequest, err
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
+	if err != nil {
+		return nil, schema.GroupVersionResource{}, http.StatusInternalServerError, err
+	}
+	gvk := schema.GroupVersionResource{Group: param["group"], Version: param["version"], Resource: param["resource"]}
+	return k8s.NewCRDOperation(k8sClient.Dynamic), gvk, http.StatusOK, nil
+}
+
+// crdBody reads the json body of a custom resource
+func crdBody(c *gin.Context) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	bytes, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 // GetCRDs
 // @Summary 获取CRD自定义资源列表
 // @accept application/json
@@ -20,41 +48,67 @@ import (
 // @Param group path string true "Group"
 // @Param version path string true "Version"
 // @Param resource path string true "Resource"
-// @Param namespace path string true "Namespace"
-// @Param name path string true "Name"
-// @Success 200 {object} app.Response
+// @Param param query metadata.CommonQueryParameter true "namespace与allNamespaces二选一"
+// @Success 200 {object} app.ResponseExtra
 // @Failure 500 {object} app.Response
-// @Router /k8s/{cluster}/crd/{group}/{version}/{resource}/{namespace} [get]
+// @Router /k8s/{cluster}/crd/{group}/{version}/{resource} [get]
 func GetCRDs(c *gin.Context) {
 	appG := app.Gin{C: c}
 	var queryParam metadata.CommonQueryParameter
-	param, err := app.GetPathParameterString(c, "cluster", "group", "version", "resource")
-	if err != nil {
+	if err := appG.C.ShouldBindQuery(&queryParam); err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	if err := appG.C.ShouldBindQuery(&queryParam); err != nil {
-		appG.Fail(http.StatusBadRequest, err, nil)
+	crdOperation, gvk, status, err := crdOperation(c, false)
+	if err != nil {
+		appG.Fail(status, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	unstructuredList, err := crdOperation.List(context.TODO(), gvk, queryParam)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	dyn, err := dynamic.NewForConfig(k8sClient.RestConfig)
+	if queryParam.Output != "" {
+		appG.SuccessTable(unstructuredList, queryParam.Limit, queryParam.Output)
+		return
+	}
+	appG.SuccessList(unstructuredList, queryParam.Limit, unstructuredList)
+}
+
+// GetClusterCRDs
+// @Summary 获取集群级别CRD自定义资源列表
+// @accept application/json
+// @Param cluster path string true "Cluster"
+// @Param group path string true "Group"
+// @Param version path string true "Version"
+// @Param resource path string true "Resource"
+// @Param param query metadata.ListParameter false "ListParameter"
+// @Success 200 {object} app.ResponseExtra
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/clustercrd/{group}/{version}/{resource} [get]
+func GetClusterCRDs(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var listParam metadata.ListParameter
+	if err := appG.C.ShouldBindQuery(&listParam); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	crdOperation, gvk, status, err := crdOperation(c, false)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(status, err, nil)
 		return
 	}
-	crdOperation := k8s.NewCRDOperation(dyn)
-	gvk := schema.GroupVersionResource{Group: param["group"], Version: param["version"], Resource: param["resource"]}
-	unstructuredList, err := crdOperation.List(context.TODO(), gvk, queryParam)
+	unstructuredList, err := crdOperation.List(context.TODO(), gvk, metadata.CommonQueryParameter{ListParameter: listParam})
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", unstructuredList)
+	if listParam.Output != "" {
+		appG.SuccessTable(unstructuredList, listParam.Limit, listParam.Output)
+		return
+	}
+	appG.SuccessList(unstructuredList, listParam.Limit, unstructuredList)
 }
 
 // GetCRD
@@ -69,26 +123,20 @@ func GetCRDs(c *gin.Context) {
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/crd/{group}/{version}/{resource}/{namespace}/{name} [get]
+// @Router /k8s/{cluster}/clustercrd/{group}/{version}/{resource}/{name} [get]
 func GetCRD(c *gin.Context) {
 	appG := app.Gin{C: c}
-	param, err := app.GetPathParameterString(c, "cluster", "group", "version", "resource", "namespace", "name")
+	param, err := app.GetPathParameterString(c, "name")
 	if err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
-	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
-		return
-	}
-	dyn, err := dynamic.NewForConfig(k8sClient.RestConfig)
+	crdOperation, gvk, status, err := crdOperation(c, false)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(status, err, nil)
 		return
 	}
-	crdOperation := k8s.NewCRDOperation(dyn)
-	gvk := schema.GroupVersionResource{Group: param["group"], Version: param["version"], Resource: param["resource"]}
-	unstructured, err := crdOperation.Get(context.TODO(), gvk, param["namespace"], param["name"])
+	unstructured, err := crdOperation.Get(context.TODO(), gvk, c.Param("namespace"), param["name"])
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -97,53 +145,86 @@ func GetCRD(c *gin.Context) {
 }
 
 // PostCRD
-// @Summary 创建CRD资源
+// @Summary 创建CRD资源，使用metadata.namespace，与namespace参数不一致时返回400
 // @accept application/json
 // @Param cluster path string true "Cluster"
 // @Param group path string true "Group"
 // @Param version path string true "Version"
 // @Param resource path string true "Resource"
+// @Param dryRun query bool false "DryRun"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
+// @Failure 400 {object} app.Response
 // @Router /k8s/{cluster}/crd/{group}/{version}/{resource} [post]
 func PostCRD(c *gin.Context) {
 	appG := app.Gin{C: c}
-	var (
-		bytes []byte
-		data  map[string]interface{}
-	)
-	param, err := app.GetPathParameterString(c, "cluster", "group", "version", "resource")
+	data, err := crdBody(c)
 	if err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	bytes, err = ioutil.ReadAll(c.Request.Body)
+	dryRun, err := app.DryRun(c)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	if err = json.Unmarshal(bytes, &data); err != nil {
+	crdOperation, gvk, status, err := crdOperation(c, dryRun)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	unstructured, err := crdOperation.Create(context.TODO(), gvk, data)
+	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(nil, unstructured.Object)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", unstructured.Object)
+}
 
-	k8sClient, err := k8s.GetClient(param["cluster"])
+// PostClusterCRD
+// @Summary 创建集群级别CRD资源，忽略metadata.namespace
+// @accept application/json
+// @Param cluster path string true "Cluster"
+// @Param group path string true "Group"
+// @Param version path string true "Version"
+// @Param resource path string true "Resource"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/clustercrd/{group}/{version}/{resource} [post]
+func PostClusterCRD(c *gin.Context) {
+	appG := app.Gin{C: c}
+	data, err := crdBody(c)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	dyn, err := dynamic.NewForConfig(k8sClient.RestConfig)
+	if metadata, ok := data["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "namespace")
+	}
+	dryRun, err := app.DryRun(c)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	crdOperation, gvk, status, err := crdOperation(c, dryRun)
+	if err != nil {
+		appG.Fail(status, err, nil)
 		return
 	}
-	crdOperation := k8s.NewCRDOperation(dyn)
-	gvk := schema.GroupVersionResource{Group: param["group"], Version: param["version"], Resource: param["resource"]}
 	unstructured, err := crdOperation.Create(context.TODO(), gvk, data)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(nil, unstructured.Object)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", unstructured.Object)
 }
 
@@ -154,48 +235,112 @@ func PostCRD(c *gin.Context) {
 // @Param group path string true "Group"
 // @Param version path string true "Version"
 // @Param resource path string true "Resource"
+// @Param dryRun query bool false "DryRun"
 // @Param namespace path string true "Namespace"
 // @Param name path string true "Name"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/crd/{group}/{version}/{resource}/{namespace}/{name} [put]
+// @Router /k8s/{cluster}/clustercrd/{group}/{version}/{resource}/{name} [put]
 func PutCRD(c *gin.Context) {
 	appG := app.Gin{C: c}
-	var (
-		bytes []byte
-		data  map[string]interface{}
-	)
-	param, err := app.GetPathParameterString(c, "cluster", "group", "version", "resource", "namespace", "name")
+	param, err := app.GetPathParameterString(c, "name")
 	if err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	bytes, err = ioutil.ReadAll(c.Request.Body)
+	data, err := crdBody(c)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	if err = json.Unmarshal(bytes, &data); err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	crdOperation, gvk, status, err := crdOperation(c, dryRun)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(status, err, nil)
 		return
 	}
-	dyn, err := dynamic.NewForConfig(k8sClient.RestConfig)
+	var live interface{}
+	if dryRun {
+		live, err = crdOperation.Get(context.TODO(), gvk, c.Param("namespace"), param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	unstructured, err := crdOperation.Update(context.TODO(), gvk, c.Param("namespace"), param["name"], data)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	crdOperation := k8s.NewCRDOperation(dyn)
-	gvk := schema.GroupVersionResource{Group: param["group"], Version: param["version"], Resource: param["resource"]}
-	unstructured, err := crdOperation.Update(context.TODO(), gvk, param["namespace"], param["name"], data)
+	if dryRun {
+		appG.SuccessDryRun(live, unstructured.Object)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", unstructured.Object)
+}
+
+// PatchCRD
+// @Summary 按Content-Type以json patch、merge patch或apply patch更新CRD自定义资源，不支持strategic merge patch
+// @accept application/json-patch+json
+// @accept application/merge-patch+json
+// @accept application/apply-patch+yaml
+// @Param cluster path string true "Cluster"
+// @Param group path string true "Group"
+// @Param version path string true "Version"
+// @Param resource path string true "Resource"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Name"
+// @Param fieldManager query string false "FieldManager"
+// @Param force query bool false "Force"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/crd/{group}/{version}/{resource}/{namespace}/{name} [patch]
+// @Router /k8s/{cluster}/clustercrd/{group}/{version}/{resource}/{name} [patch]
+func PatchCRD(c *gin.Context) {
+	appG := app.Gin{C: c}
+	param, err := app.GetPathParameterString(c, "name")
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	patchType, data, opts, status, err := patchRequest(c)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	crdOperation, gvk, status, err := crdOperation(c, dryRun)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	var live interface{}
+	if dryRun {
+		live, err = crdOperation.Get(context.TODO(), gvk, c.Param("namespace"), param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	unstructured, err := crdOperation.Patch(context.TODO(), gvk, c.Param("namespace"), param["name"], patchType, data, opts)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, unstructured.Object)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", unstructured.Object)
 }
 
@@ -206,34 +351,46 @@ func PutCRD(c *gin.Context) {
 // @Param group path string true "Group"
 // @Param version path string true "Version"
 // @Param resource path string true "Resource"
+// @Param dryRun query bool false "DryRun"
 // @Param namespace path string true "Namespace"
 // @Param name path string true "Name"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/crd/{group}/{version}/{resource}/{namespace}/{name} [delete]
+// @Router /k8s/{cluster}/clustercrd/{group}/{version}/{resource}/{name} [delete]
 func DeleteCRD(c *gin.Context) {
 	appG := app.Gin{C: c}
-	param, err := app.GetPathParameterString(c, "cluster", "group", "version", "resource", "namespace", "name")
+	param, err := app.GetPathParameterString(c, "name")
 	if err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	dryRun, err := app.DryRun(c)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	dyn, err := dynamic.NewForConfig(k8sClient.RestConfig)
+	crdOperation, gvk, status, err := crdOperation(c, dryRun)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(status, err, nil)
 		return
 	}
-	crdOperation := k8s.NewCRDOperation(dyn)
-	gvk := schema.GroupVersionResource{Group: param["group"], Version: param["version"], Resource: param["resource"]}
-	err = crdOperation.Delete(context.TODO(), gvk, param["namespace"], param["name"])
+	var live interface{}
+	if dryRun {
+		live, err = crdOperation.Get(context.TODO(), gvk, c.Param("namespace"), param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	err = crdOperation.Delete(context.TODO(), gvk, c.Param("namespace"), param["name"])
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", nil)
 }
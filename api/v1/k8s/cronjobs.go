@@ -8,13 +8,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	"k8s.io/api/batch/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type CronJobsQuery struct {
-	Namespace string `form:"namespace"`
-	Label     string `form:"label"`
+	metadata.NamespaceListParameter
+	Label string `form:"label"` // 同labelSelector
 }
 
 type CronJobsUri struct {
@@ -54,22 +55,25 @@ func GetCronJobs(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	if q.Label == "" {
-		listOpts = metav1.ListOptions{}
-	} else {
-		listOpts = metav1.ListOptions{LabelSelector: q.Label}
+	listOpts = q.ListOptions()
+	if q.Label != "" {
+		listOpts.LabelSelector = q.Label
 	}
-	cronjobs, err := k8sClient.ClientV1.BatchV1beta1().CronJobs(q.Namespace).List(context.TODO(), listOpts)
+	cronjobs, err := k8sClient.ClientV1.BatchV1beta1().CronJobs(q.ListNamespace()).List(context.TODO(), listOpts)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", cronjobs)
+	if q.Output != "" {
+		appG.SuccessTable(cronjobs, q.Limit, q.Output)
+		return
+	}
+	appG.SuccessList(cronjobs, q.Limit, cronjobs)
 }
 
 func GetCronJob(c *gin.Context) {
@@ -84,7 +88,7 @@ func GetCronJob(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -118,7 +122,12 @@ func PostCronJob(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -129,6 +138,10 @@ func PostCronJob(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(nil, cronjob)
+		return
+	}
 
 	appG.Success(http.StatusOK, "Created CronJob Successfully", cronjob)
 }
@@ -150,49 +163,155 @@ func PutCronJob(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	expected, err := app.ExpectedResourceVersion(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	cronJobs := k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace)
 	if b.Label == "" {
-		cronjob, err := k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace).Get(context.TODO(), u.CronJobName, metav1.GetOptions{})
+		var live, result *v1beta1.CronJob
+		err = k8s.RetryOnConflict(expected, func() error {
+			cronjob, err := cronJobs.Get(context.TODO(), u.CronJobName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if err := k8s.CheckResourceVersion(cronjob, expected, v1beta1.Resource("cronjobs")); err != nil {
+				return err
+			}
+			live = cronjob.DeepCopy()
+			cronjob.Spec = b.Spec
+			result, err = cronJobs.Update(context.TODO(), cronjob, metav1.UpdateOptions{})
+			return err
+		})
 		if err != nil {
 			appG.Fail(http.StatusInternalServerError, err, nil)
 			return
 		}
-		cronjob.Spec = b.Spec
-		_, err = k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace).Update(context.TODO(), cronjob, metav1.UpdateOptions{})
-		if err != nil {
-			appG.Fail(http.StatusInternalServerError, err, nil)
+		if dryRun {
+			appG.SuccessDryRun(live, result)
 			return
 		}
 	} else {
+		if expected != "" {
+			appG.Fail(http.StatusBadRequest, errors.New("resourceVersion and If-Match apply to a single cronjob, not to label"), nil)
+			return
+		}
 		//bulk update
 		listOpts = metav1.ListOptions{LabelSelector: b.Label}
-		cronjobs, err := k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace).List(context.TODO(), listOpts)
+		cronjobs, err := cronJobs.List(context.TODO(), listOpts)
 		if err != nil {
 			appG.Fail(http.StatusInternalServerError, err, nil)
 			return
 		}
-		for _, cronjob := range cronjobs.Items {
-			containers := cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers
-			if len(containers) != 1 {
-				appG.Fail(http.StatusInternalServerError, errors.New(cronjob.Name+" containers more than 2, unkown which one to update, please check"), nil)
-				return
-			}
-			cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image = b.Image
-			_, err = k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace).Update(context.TODO(), &cronjob, metav1.UpdateOptions{})
+		dryRunResults := make([]*k8s.DryRunResult, 0, len(cronjobs.Items))
+		for _, item := range cronjobs.Items {
+			name := item.Name
+			var live, result *v1beta1.CronJob
+			err := k8s.RetryOnConflict("", func() error {
+				cronjob, err := cronJobs.Get(context.TODO(), name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				if len(cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers) != 1 {
+					return errors.New(cronjob.Name + " containers more than 2, unkown which one to update, please check")
+				}
+				live = cronjob.DeepCopy()
+				cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image = b.Image
+				result, err = cronJobs.Update(context.TODO(), cronjob, metav1.UpdateOptions{})
+				return err
+			})
 			if err != nil {
 				appG.Fail(http.StatusInternalServerError, err, nil)
 				return
 			}
+			if dryRun {
+				dryRunResult, err := k8s.NewDryRunResult(live, result)
+				if err != nil {
+					appG.Fail(http.StatusInternalServerError, err, nil)
+					return
+				}
+				dryRunResults = append(dryRunResults, dryRunResult)
+			}
+		}
+		if dryRun {
+			appG.Success(http.StatusOK, "dry run", dryRunResults)
+			return
 		}
 	}
 
 	appG.Success(http.StatusOK, "Updated CronJob Successfully", nil)
 }
 
+// PatchCronJob
+// @Summary 按Content-Type以json patch、merge patch、strategic merge patch或apply patch更新cronjob
+// @accept application/json-patch+json
+// @accept application/merge-patch+json
+// @accept application/strategic-merge-patch+json
+// @accept application/apply-patch+yaml
+// @Param cluster path string true "Cluster"
+// @Param namespace path string true "Namespace"
+// @Param cronjobName path string true "CronJobName"
+// @Param fieldManager query string false "FieldManager"
+// @Param force query bool false "Force"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/cronjobs/{namespace}/{cronjobName} [patch]
+func PatchCronJob(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var u CronJobUri
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	patchType, data, opts, status, err := patchRequest(c)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	var live interface{}
+	if dryRun {
+		live, err = k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace).Get(context.TODO(), u.CronJobName, metav1.GetOptions{})
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	result, err := k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace).Patch(context.TODO(), u.CronJobName, patchType, data, opts)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", result)
+}
+
 func DeleteCronJob(c *gin.Context) {
 	appG := app.Gin{C: c}
 
@@ -205,16 +324,33 @@ func DeleteCronJob(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	var live interface{}
+	if dryRun {
+		live, err = k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace).Get(context.TODO(), u.CronJobName, metav1.GetOptions{})
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	propagationPolicy := metav1.DeletePropagationBackground
 	err = k8sClient.ClientV1.BatchV1beta1().CronJobs(u.Namespace).Delete(context.TODO(), u.CronJobName, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 	appG.Success(http.StatusOK, "Deleted CronJob Successfully", nil)
 }
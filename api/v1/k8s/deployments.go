@@ -12,13 +12,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type DeploymentsQuery struct {
-	Namespace string `form:"namespace"`
-	Label     string `form:"label"`
+	metadata.NamespaceListParameter
+	Label string `form:"label"` // 同labelSelector
 }
 
 type DeploymentActionQuery struct {
@@ -51,9 +53,13 @@ var DeploymentKind = "Deployment"
 // @Summary 查看deployment列表
 // @Produce  json
 // @Param cluster path string true "Cluster"
-// @Param namespace query string true "Namespace"
+// @Param namespace query string false "Namespace，为空时查询全部namespace"
+// @Param allNamespaces query bool false "AllNamespaces"
 // @Param label query string false "Label"
-// @Success 200 {object} app.Response
+// @Param limit query int false "Limit"
+// @Param continue query string false "上一页返回的continue"
+// @Param output query string false "table或wide，返回kubectl get的表格"
+// @Success 200 {object} app.ResponseExtra
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/deployments [get]
 func GetDeployments(c *gin.Context) {
@@ -72,27 +78,29 @@ func GetDeployments(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	if q.Label == "" {
-		listOpts = metav1.ListOptions{}
-	} else {
-		listOpts = metav1.ListOptions{LabelSelector: q.Label}
+	listOpts = q.ListOptions()
+	if q.Label != "" {
+		listOpts.LabelSelector = q.Label
 	}
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	deployments, err := k8sClient.ClientV1.AppsV1().Deployments(q.Namespace).List(context.TODO(), listOpts)
-	for i := 0; i < len(deployments.Items); i++ {
-		deployments.Items[i].CreationTimestamp = metav1.NewTime(deployments.Items[i].CreationTimestamp.Add(8 * time.Hour))
-	}
-
+	deployments, err := k8sClient.ClientV1.AppsV1().Deployments(q.ListNamespace()).List(context.TODO(), listOpts)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", deployments)
+	if q.Output != "" {
+		appG.SuccessTable(deployments, q.Limit, q.Output)
+		return
+	}
+	for i := 0; i < len(deployments.Items); i++ {
+		deployments.Items[i].CreationTimestamp = metav1.NewTime(deployments.Items[i].CreationTimestamp.Add(8 * time.Hour))
+	}
+	appG.SuccessList(deployments, q.Limit, deployments)
 }
 
 // @Summary 查看deployment
@@ -114,7 +122,7 @@ func GetDeployment(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -153,7 +161,12 @@ func PostDeployment(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -165,6 +178,10 @@ func PostDeployment(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(nil, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
 
@@ -186,57 +203,69 @@ func DeploymentDoAction(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
 	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
+		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-
-	deployment, err := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Get(context.TODO(), u.DeploymentName, metav1.GetOptions{})
+	expected, err := app.ExpectedResourceVersion(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
 	switch q.Action {
-	case "redeploy":
-		if deployment.Spec.Paused {
-			appG.Fail(http.StatusInternalServerError, errors.New("can't restart paused deployment (run rollout resume first)"), nil)
-			return
-		}
-		if deployment.Spec.Template.ObjectMeta.Annotations == nil {
-			deployment.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
-		}
-		deployment.Spec.Template.ObjectMeta.Annotations["kubectl.kubernetes.io/restartedAt"] = time.Now().String()
-		_, err := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{})
-		if err != nil {
-			appG.Fail(http.StatusInternalServerError, err, nil)
-			return
-		}
-	case "pause":
-		if deployment.Spec.Paused {
-			appG.Fail(http.StatusInternalServerError, errors.New("deployment is already paused"), nil)
-			return
-		}
-		deployment.Spec.Paused = true
-		_, err := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{})
+	case "redeploy", "pause", "resume":
+	default:
+		appG.Fail(http.StatusBadRequest, errors.New("Invalid parameter, must be redeploy|pause|resume"), nil)
+		return
+	}
+
+	deployments := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace)
+	var live, result *appsv1.Deployment
+	err = k8s.RetryOnConflict(expected, func() error {
+		deployment, err := deployments.Get(context.TODO(), u.DeploymentName, metav1.GetOptions{})
 		if err != nil {
-			appG.Fail(http.StatusInternalServerError, err, nil)
-			return
+			return err
 		}
-	case "resume":
-		if !deployment.Spec.Paused {
-			appG.Fail(http.StatusInternalServerError, errors.New("deployment is not paused"), nil)
-			return
+		if err := k8s.CheckResourceVersion(deployment, expected, appsv1.Resource("deployments")); err != nil {
+			return err
 		}
-		deployment.Spec.Paused = false
-		_, err := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{})
-		if err != nil {
-			appG.Fail(http.StatusInternalServerError, err, nil)
-			return
+		live = deployment.DeepCopy()
+		switch q.Action {
+		case "redeploy":
+			if deployment.Spec.Paused {
+				return errors.New("can't restart paused deployment (run rollout resume first)")
+			}
+			if deployment.Spec.Template.ObjectMeta.Annotations == nil {
+				deployment.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+			}
+			deployment.Spec.Template.ObjectMeta.Annotations["kubectl.kubernetes.io/restartedAt"] = time.Now().String()
+		case "pause":
+			if deployment.Spec.Paused {
+				return errors.New("deployment is already paused")
+			}
+			deployment.Spec.Paused = true
+		case "resume":
+			if !deployment.Spec.Paused {
+				return errors.New("deployment is not paused")
+			}
+			deployment.Spec.Paused = false
 		}
-	default:
-		appG.Fail(http.StatusBadRequest, errors.New("Invalid parameter, must be redeploy|pause|resume"), nil)
+		result, err = deployments.Update(context.TODO(), deployment, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
 		return
 	}
 
@@ -250,7 +279,10 @@ func DeploymentDoAction(c *gin.Context) {
 // @Param namespace path string true "Namespace"
 // @Param deploymentName path string true "DeploymentName"
 // @Param RequestBody body v1.DeploymentBody true "RequestBody"
+// @Param resourceVersion query string false "期望的resourceVersion，已变更时返回409"
+// @Param If-Match header string false "同resourceVersion"
 // @Success 200 {object} app.Response
+// @Failure 409 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/deployments/{namespace}/{deploymentName} [put]
 func PutDeployment(c *gin.Context) {
@@ -268,15 +300,24 @@ func PutDeployment(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	expected, err := app.ExpectedResourceVersion(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
+	deployments := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace)
 	if b.Label == "" {
-		deployment, err := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Get(context.TODO(), u.DeploymentName, metav1.GetOptions{})
-
 		// update replicas
 		if b.Replicas != "" {
 			replicas, err := strconv.ParseInt(b.Replicas, 10, 32)
@@ -284,47 +325,105 @@ func PutDeployment(c *gin.Context) {
 				appG.Fail(http.StatusInternalServerError, err, nil)
 				return
 			}
-			r := int32(replicas)
-			sc, err := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).GetScale(context.TODO(), u.DeploymentName, metav1.GetOptions{})
-			sc.Spec.Replicas = r
-			_, err = k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).UpdateScale(context.TODO(), u.DeploymentName, sc, metav1.UpdateOptions{})
+			var liveScale, resultScale *autoscalingv1.Scale
+			err = k8s.RetryOnConflict(expected, func() error {
+				sc, err := deployments.GetScale(context.TODO(), u.DeploymentName, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				if err := k8s.CheckResourceVersion(sc, expected, appsv1.Resource("deployments")); err != nil {
+					return err
+				}
+				liveScale = sc.DeepCopy()
+				sc.Spec.Replicas = int32(replicas)
+				resultScale, err = deployments.UpdateScale(context.TODO(), u.DeploymentName, sc, metav1.UpdateOptions{})
+				return err
+			})
 			if err != nil {
 				appG.Fail(http.StatusInternalServerError, err, nil)
 				return
 			}
+			if dryRun {
+				appG.SuccessDryRun(liveScale, resultScale)
+				return
+			}
 			appG.Success(http.StatusOK, "deployment replicas update to "+b.Replicas, nil)
 			return
 		}
 
-		// update image
-		if b.Image != "" {
-			deployment.Spec.Template.Spec.Containers[0].Image = b.Image
-		}
+		var live, result *appsv1.Deployment
+		err = k8s.RetryOnConflict(expected, func() error {
+			deployment, err := deployments.Get(context.TODO(), u.DeploymentName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if err := k8s.CheckResourceVersion(deployment, expected, appsv1.Resource("deployments")); err != nil {
+				return err
+			}
+			live = deployment.DeepCopy()
 
-		// force update
-		ForceUpdate(deployment)
+			// update image
+			if b.Image != "" {
+				deployment.Spec.Template.Spec.Containers[0].Image = b.Image
+			}
 
-		_, err = k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{})
+			// force update
+			ForceUpdate(deployment)
+
+			result, err = deployments.Update(context.TODO(), deployment, metav1.UpdateOptions{})
+			return err
+		})
 		if err != nil {
 			appG.Fail(http.StatusInternalServerError, err, nil)
 			return
 		}
+		if dryRun {
+			appG.SuccessDryRun(live, result)
+			return
+		}
 	} else {
+		if expected != "" {
+			appG.Fail(http.StatusBadRequest, errors.New("resourceVersion and If-Match apply to a single deployment, not to label"), nil)
+			return
+		}
 		listOpts = metav1.ListOptions{LabelSelector: b.Label}
-		deployments, err := k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).List(context.TODO(), listOpts)
+		list, err := deployments.List(context.TODO(), listOpts)
 		if err != nil {
 			appG.Fail(http.StatusInternalServerError, err, nil)
 			return
 		}
-		for _, deployment := range deployments.Items {
-			deployment.Spec.Template.Spec.Containers[0].Image = b.Image
-			// force update
-			ForceUpdate(&deployment)
-			_, err = k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Update(context.TODO(), &deployment, metav1.UpdateOptions{})
+		dryRunResults := make([]*k8s.DryRunResult, 0, len(list.Items))
+		for _, item := range list.Items {
+			name := item.Name
+			var live, result *appsv1.Deployment
+			err := k8s.RetryOnConflict("", func() error {
+				deployment, err := deployments.Get(context.TODO(), name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				live = deployment.DeepCopy()
+				deployment.Spec.Template.Spec.Containers[0].Image = b.Image
+				// force update
+				ForceUpdate(deployment)
+				result, err = deployments.Update(context.TODO(), deployment, metav1.UpdateOptions{})
+				return err
+			})
 			if err != nil {
 				appG.Fail(http.StatusInternalServerError, err, nil)
 				return
 			}
+			if dryRun {
+				dryRunResult, err := k8s.NewDryRunResult(live, result)
+				if err != nil {
+					appG.Fail(http.StatusInternalServerError, err, nil)
+					return
+				}
+				dryRunResults = append(dryRunResults, dryRunResult)
+			}
+		}
+		if dryRun {
+			appG.Success(http.StatusOK, "dry run", dryRunResults)
+			return
 		}
 	}
 
@@ -333,39 +432,62 @@ func PutDeployment(c *gin.Context) {
 }
 
 // PatchDeployment
-// @Summary 批量更新deployment
-// @Produce  json
+// @Summary 按Content-Type以json patch、merge patch、strategic merge patch或apply patch更新deployment
+// @accept application/json-patch+json
+// @accept application/merge-patch+json
+// @accept application/strategic-merge-patch+json
+// @accept application/apply-patch+yaml
 // @Param cluster path string true "Cluster"
 // @Param namespace path string true "Namespace"
 // @Param deploymentName path string true "DeploymentName"
+// @Param fieldManager query string false "FieldManager"
+// @Param force query bool false "Force"
+// @Param dryRun query bool false "DryRun"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/deployments/{namespace}/{deploymentName} [patch]
 func PatchDeployment(c *gin.Context) {
 	appG := app.Gin{C: c}
-	var deployment appsv1.Deployment
 
 	params, err := app.GetPathParameterString(c, "cluster", "namespace", "deploymentName")
 	if err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	if err := appG.C.ShouldBind(&deployment); err != nil {
+	patchType, data, opts, status, err := patchRequest(c)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(params["cluster"])
+	k8sClient, err := app.ClientAs(c, params["cluster"], dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
 	operation := k8s.NewDeploymentOperation(k8sClient.ClientV1)
-	result, err := operation.Update(context.TODO(), params["namespace"], params["deploymentName"], &deployment)
+	var live interface{}
+	if dryRun {
+		live, err = operation.Get(context.TODO(), params["namespace"], params["deploymentName"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	result, err := operation.Patch(context.TODO(), params["namespace"], params["deploymentName"], patchType, data, opts)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
 
@@ -379,18 +501,35 @@ func DeleteDeployment(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
+	var live interface{}
+	if dryRun {
+		live, err = k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Get(context.TODO(), u.DeploymentName, metav1.GetOptions{})
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	err = k8sClient.ClientV1.AppsV1().Deployments(u.Namespace).Delete(context.TODO(), u.DeploymentName, metav1.DeleteOptions{})
 
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", nil)
 }
 
@@ -412,7 +551,7 @@ func GetDeploymentStatus(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -512,7 +651,7 @@ func GetDeploymentPods(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -21,6 +22,7 @@ type EventsQuery struct {
 	Name      string `json:"name" form:"name" binding:"required"`
 	Kind      string `json:"kind" form:"kind" binding:"required"`
 	Uid       string `json:"uid" form:"uid" binding:"required"`
+	metadata.ListParameter
 }
 
 func GetEvents(c *gin.Context) {
@@ -39,25 +41,31 @@ func GetEvents(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	listOpts = q.ListOptions()
 	listOpts.FieldSelector = fmt.Sprintf(
 		"involvedObject.name=%s,involvedObject.namespace=%s,involvedObject.kind=%s,involvedObject.uid=%s",
 		q.Name, q.Namespace, q.Kind, q.Uid,
 	)
+	if q.FieldSelector != "" {
+		listOpts.FieldSelector += "," + q.FieldSelector
+	}
 	listOpts.TypeMeta = metav1.TypeMeta{Kind: q.Kind}
 	events, err := k8sClient.ClientV1.CoreV1().Events(q.Namespace).List(context.TODO(), listOpts)
-	for i := 0; i < len(events.Items); i++ {
-		events.Items[i].CreationTimestamp = metav1.NewTime(events.Items[i].CreationTimestamp.Add(8 * time.Hour))
-	}
-
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-
-	appG.Success(http.StatusOK, "ok", events)
+	if q.Output != "" {
+		appG.SuccessTable(events, q.Limit, q.Output)
+		return
+	}
+	for i := 0; i < len(events.Items); i++ {
+		events.Items[i].CreationTimestamp = metav1.NewTime(events.Items[i].CreationTimestamp.Add(8 * time.Hour))
+	}
+	appG.SuccessList(events, q.Limit, events)
 }
@@ -32,7 +32,12 @@ func PostHorizontalPodAutoScaler(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -44,6 +49,10 @@ func PostHorizontalPodAutoScaler(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(nil, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
 
@@ -51,8 +60,8 @@ func PostHorizontalPodAutoScaler(c *gin.Context) {
 // @Summary 获取弹性伸缩资源列表
 // @accept application/json
 // @Param cluster path string true "Cluster"
-// @Param param query metadata.CommonQueryParameter true "LabelSelector"
-// @Success 200 {object} app.Response
+// @Param param query metadata.CommonQueryParameter true "namespace与allNamespaces二选一"
+// @Success 200 {object} app.ResponseExtra
 // @Failure 500 {object} app.Response
 // @Router /k8s/{cluster}/horizontalpodautoscalers [get]
 func GetHorizontalPodAutoScalerList(c *gin.Context) {
@@ -67,7 +76,7 @@ func GetHorizontalPodAutoScalerList(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(pathParam["cluster"])
+	k8sClient, err := k8s.GetClientAs(pathParam["cluster"], app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -79,7 +88,11 @@ func GetHorizontalPodAutoScalerList(c *gin.Context) {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", result)
+	if queryParam.Output != "" {
+		appG.SuccessTable(result, queryParam.Limit, queryParam.Output)
+		return
+	}
+	appG.SuccessList(result, queryParam.Limit, result.Items)
 }
 
 // GetHorizontalPodAutoScaler
@@ -98,7 +111,7 @@ func GetHorizontalPodAutoScaler(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	k8sClient, err := k8s.GetClientAs(param["cluster"], app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -133,17 +146,92 @@ func PutHorizontalPodAutoScaler(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 	operation := k8s.NewHorizontalPodAutoScalerOperation(k8sClient.ClientV1)
+	var live interface{}
+	if dryRun {
+		live, err = operation.Get(context.TODO(), param["namespace"], param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	result, err := operation.Update(context.TODO(), param["namespace"], param["name"], &scaler)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", result)
+}
+
+// PatchHorizontalPodAutoScaler
+// @Summary 按Content-Type以json patch、merge patch、strategic merge patch或apply patch更新弹性伸缩资源
+// @accept application/json-patch+json
+// @accept application/merge-patch+json
+// @accept application/strategic-merge-patch+json
+// @accept application/apply-patch+yaml
+// @Param cluster path string true "Cluster"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Name"
+// @Param fieldManager query string false "FieldManager"
+// @Param force query bool false "Force"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/horizontalpodautoscalers/{namespace}/{name} [patch]
+func PatchHorizontalPodAutoScaler(c *gin.Context) {
+	appG := app.Gin{C: c}
+	param, err := app.GetPathParameterString(c, "cluster", "namespace", "name")
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	patchType, data, opts, status, err := patchRequest(c)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	operation := k8s.NewHorizontalPodAutoScalerOperation(k8sClient.ClientV1)
+	var live interface{}
+	if dryRun {
+		live, err = operation.Get(context.TODO(), param["namespace"], param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	result, err := operation.Patch(context.TODO(), param["namespace"], param["name"], patchType, data, opts)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(live, result)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", result)
 }
 
@@ -163,17 +251,34 @@ func DeleteHorizontalPodAutoScaler(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(param["cluster"])
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, param["cluster"], dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
 	operation := k8s.NewHorizontalPodAutoScalerOperation(k8sClient.ClientV1)
+	var live interface{}
+	if dryRun {
+		live, err = operation.Get(context.TODO(), param["namespace"], param["name"])
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	err = operation.Delete(context.TODO(), param["namespace"], param["name"])
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", nil)
 }
@@ -7,12 +7,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type JobsQuery struct {
-	Namespace string `form:"namespace"`
-	Label     string `form:"label"`
+	metadata.NamespaceListParameter
+	Label string `form:"label"` // 同labelSelector
 }
 
 type JobsUri struct {
@@ -43,22 +44,25 @@ func GetJobs(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	if q.Label == "" {
-		listOpts = metav1.ListOptions{}
-	} else {
-		listOpts = metav1.ListOptions{LabelSelector: q.Label}
+	listOpts = q.ListOptions()
+	if q.Label != "" {
+		listOpts.LabelSelector = q.Label
 	}
-	jobs, err := k8sClient.ClientV1.BatchV1().Jobs(q.Namespace).List(context.TODO(), listOpts)
+	jobs, err := k8sClient.ClientV1.BatchV1().Jobs(q.ListNamespace()).List(context.TODO(), listOpts)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", jobs)
+	if q.Output != "" {
+		appG.SuccessTable(jobs, q.Limit, q.Output)
+		return
+	}
+	appG.SuccessList(jobs, q.Limit, jobs)
 }
 
 func GetJob(c *gin.Context) {
@@ -73,7 +77,7 @@ func GetJob(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -98,17 +102,34 @@ func DeleteJob(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	var live interface{}
+	if dryRun {
+		live, err = k8sClient.ClientV1.BatchV1().Jobs(u.Namespace).Get(context.TODO(), u.JobName, metav1.GetOptions{})
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	propagationPolicy := metav1.DeletePropagationBackground
 	err = k8sClient.ClientV1.BatchV1().Jobs(u.Namespace).Delete(context.TODO(), u.JobName, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 
 	appG.Success(http.StatusOK, "ok", nil)
 }
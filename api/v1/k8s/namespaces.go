@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 )
 
 type NamespacesUri struct {
@@ -18,22 +18,31 @@ func GetNamespaces(c *gin.Context) {
 	appG := app.Gin{C: c}
 	var (
 		u NamespacesUri
+		q metadata.ListParameter
 	)
 	if err := appG.C.ShouldBindUri(&u); err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	namespaces, err := k8sClient.ClientV1.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	namespaces, err := k8sClient.ClientV1.CoreV1().Namespaces().List(context.TODO(), q.ListOptions())
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", namespaces)
+	if q.Output != "" {
+		appG.SuccessTable(namespaces, q.Limit, q.Output)
+		return
+	}
+	appG.SuccessList(namespaces, q.Limit, namespaces)
 }
@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 )
 
 type NodesUri struct {
@@ -18,21 +18,30 @@ func GetNodes(c *gin.Context) {
 	appG := app.Gin{C: c}
 	var (
 		u NodesUri
+		q metadata.ListParameter
 	)
 	if err := appG.C.ShouldBindUri(&u); err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	deployments, err := k8sClient.ClientV1.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	deployments, err := k8sClient.ClientV1.CoreV1().Nodes().List(context.TODO(), q.ListOptions())
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", deployments)
+	if q.Output != "" {
+		appG.SuccessTable(deployments, q.Limit, q.Output)
+		return
+	}
+	appG.SuccessList(deployments, q.Limit, deployments)
 }
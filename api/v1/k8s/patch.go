@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type PatchQuery struct {
+	FieldManager string `form:"fieldManager"` // 默认使用配置apply.fieldManager
+	Force        bool   `form:"force"`        // 仅apply-patch，强制接管冲突字段
+}
+
+// patchRequest reads the patch body, its type from Content-Type and the
+// patch options from the query
+func patchRequest(c *gin.Context) (types.PatchType, []byte, metav1.PatchOptions, int, error) {
+	var q PatchQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		return "", nil, metav1.PatchOptions{}, http.StatusBadRequest, err
+	}
+	patchType, err := k8s.PatchType(c.ContentType())
+	if err != nil {
+		return "", nil, metav1.PatchOptions{}, http.StatusUnsupportedMediaType, err
+	}
+	data, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", nil, metav1.PatchOptions{}, http.StatusBadRequest, err
+	}
+	if len(data) == 0 {
+		return "", nil, metav1.PatchOptions{}, http.StatusBadRequest, errors.New("patch body is empty")
+	}
+	if q.FieldManager == "" {
+		q.FieldManager = config.ApplyFieldManager()
+	}
+	return patchType, data, k8s.PatchOptions(patchType, q.FieldManager, q.Force), http.StatusOK, nil
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -24,8 +25,8 @@ import (
 )
 
 type PodsQuery struct {
-	Namespace string `form:"namespace"`
-	Label     string `form:"label"`
+	metadata.NamespaceListParameter
+	Label string `form:"label"` // 同labelSelector
 }
 
 type PodsUri struct {
@@ -59,9 +60,8 @@ type ExtraPodResp struct {
 
 // upgrade websocket
 var upGrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	CheckOrigin:  app.CheckOrigin,
+	Subprotocols: []string{app.WSTokenProtocol},
 }
 
 func GetPods(c *gin.Context) {
@@ -79,19 +79,26 @@ func GetPods(c *gin.Context) {
 		appG.Fail(http.StatusBadRequest, err, nil)
 		return
 	}
-	if q.Label == "" {
-		listOpts = metav1.ListOptions{}
-	} else {
-		listOpts = metav1.ListOptions{LabelSelector: q.Label}
+	listOpts = q.ListOptions()
+	if q.Label != "" {
+		listOpts.LabelSelector = q.Label
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	pods, err := k8sClient.ClientV1.CoreV1().Pods(q.Namespace).List(context.TODO(), listOpts)
+	pods, err := k8sClient.ClientV1.CoreV1().Pods(q.ListNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if q.Output != "" {
+		appG.SuccessTable(pods, q.Limit, q.Output)
+		return
+	}
 	for i := 0; i < len(pods.Items); i++ {
 		pods.Items[i].CreationTimestamp = metav1.NewTime(pods.Items[i].CreationTimestamp.Add(8 * time.Hour))
 	}
@@ -114,11 +121,7 @@ func GetPods(c *gin.Context) {
 		ListMeta: pods.ListMeta,
 		Items:    newPodItems,
 	}
-	if err != nil {
-		appG.Fail(http.StatusInternalServerError, err, nil)
-		return
-	}
-	appG.Success(http.StatusOK, "ok", newPodList)
+	appG.SuccessList(pods, q.Limit, newPodList)
 }
 
 func WatchPods(c *gin.Context) {
@@ -153,13 +156,13 @@ func WatchPods(c *gin.Context) {
 		listOpts = metav1.ListOptions{LabelSelector: q.Label}
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.C.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	w, err := k8sClient.ClientV1.CoreV1().Pods(q.Namespace).Watch(context.TODO(), listOpts)
+	w, err := k8sClient.StreamClientV1.CoreV1().Pods(q.Namespace).Watch(context.TODO(), listOpts)
 	if err != nil {
 		appG.C.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -218,7 +221,7 @@ func GetPod(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
@@ -244,18 +247,35 @@ func DeletePod(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
 
+	var live interface{}
+	if dryRun {
+		live, err = k8sClient.ClientV1.CoreV1().Pods(u.Namespace).Get(context.TODO(), u.PodName, metav1.GetOptions{})
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
 	err = k8sClient.ClientV1.CoreV1().Pods(u.Namespace).Delete(context.TODO(), u.PodName, metav1.DeleteOptions{})
 
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
 	appG.Success(http.StatusOK, "ok", nil)
 
 }
@@ -306,13 +326,13 @@ func GetPodLog(c *gin.Context) {
 		TailLines:  &tailLines,
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.C.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	req := k8sClient.ClientV1.CoreV1().Pods(u.Namespace).GetLogs(u.PodName, &podLogOps)
+	req := k8sClient.StreamClientV1.CoreV1().Pods(u.Namespace).GetLogs(u.PodName, &podLogOps)
 	readCloser, err := req.Stream(context.TODO())
 	if err != nil {
 		appG.C.AbortWithError(http.StatusInternalServerError, err)
@@ -366,7 +386,7 @@ func DownloadPodContainerLog(c *gin.Context) {
 	if err != nil {
 		appG.Fail(http.StatusBadRequest, err, nil)
 	}
-	k8sClient, err := k8s.GetClient(params["cluster"])
+	k8sClient, err := k8s.GetClientAs(params["cluster"], app.Impersonation(c))
 	if err != nil {
 		appG.C.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -410,7 +430,7 @@ func PodWebSSH(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.C.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -442,9 +462,11 @@ func PodWebSSH(c *gin.Context) {
 	executor, err := remotecommand.NewSPDYExecutor(k8sClient.RestConfig, "POST", sshReq.URL())
 	if err != nil {
 		appG.C.AbortWithError(http.StatusInternalServerError, err)
+		return
 	}
 
 	// Data flow processing callback between configuration and container
+	endSession := app.AuditSession(c)
 	err = executor.Stream(remotecommand.StreamOptions{
 		Stdin:             t.Stdin(),
 		Stdout:            t.Stdout(),
@@ -452,6 +474,7 @@ func PodWebSSH(c *gin.Context) {
 		Tty:               t.Tty(),
 		TerminalSizeQueue: t,
 	})
+	endSession(err)
 
 	if err != nil {
 		t.wsConn.Close()
@@ -0,0 +1,353 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type ResourcesUri struct {
+	Cluster string `uri:"cluster" binding:"required"`
+	Kind    string `uri:"kind" binding:"required"`
+}
+
+type ResourceUri struct {
+	Cluster string `uri:"cluster" binding:"required"`
+	Kind    string `uri:"kind" binding:"required"`
+	Name    string `uri:"name" binding:"required"`
+}
+
+type ResourcesQuery struct {
+	metadata.NamespaceListParameter // 集群级别资源忽略namespace
+}
+
+type ResourceQuery struct {
+	Namespace string `form:"namespace"` // 集群级别资源忽略
+}
+
+// resourceOperation resolves kind on the cluster, unknown kinds fail with 404.
+// Writes are not persisted when dryRun is set.
+func resourceOperation(c *gin.Context, cluster, kind string, dryRun bool) (*k8s.ResourceOperation, int, error) {
+	k8sClient, err := app.ClientAs(c, cluster, dryRun)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	mapping, err := k8s.ResourceMapping(cluster, kind)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	return k8s.NewResourceOperation(k8sClient.Dynamic, mapping), http.StatusOK, nil
+}
+
+// GetResources
+// @Summary 获取任意类型资源列表，kind支持简称、单复数及kind名称
+// @Param cluster path string true "Cluster"
+// @Param kind path string true "Kind"
+// @Param param query ResourcesQuery false "namespace为空或allNamespaces时查询全部namespace"
+// @Success 200 {object} app.ResponseExtra
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/resources/{kind} [get]
+func GetResources(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u ResourcesUri
+		q ResourcesQuery
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	operation, status, err := resourceOperation(c, u.Cluster, u.Kind, false)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	list, err := operation.List(context.TODO(), q.ListNamespace(), q.ListOptions())
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if q.Output != "" {
+		appG.SuccessTable(list, q.Limit, q.Output)
+		return
+	}
+	appG.SuccessList(list, q.Limit, list)
+}
+
+// GetResource
+// @Summary 获取任意类型资源
+// @Param cluster path string true "Cluster"
+// @Param kind path string true "Kind"
+// @Param name path string true "Name"
+// @Param namespace query string false "Namespace"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/resources/{kind}/{name} [get]
+func GetResource(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u ResourceUri
+		q ResourceQuery
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	operation, status, err := resourceOperation(c, u.Cluster, u.Kind, false)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	obj, err := operation.Get(context.TODO(), q.Namespace, u.Name)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", obj.Object)
+}
+
+// PostResource
+// @Summary 创建任意类型资源，namespace参数为空时使用metadata.namespace
+// @accept application/json
+// @Param cluster path string true "Cluster"
+// @Param kind path string true "Kind"
+// @Param namespace query string false "Namespace"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/resources/{kind} [post]
+func PostResource(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u   ResourcesUri
+		q   ResourceQuery
+		obj unstructured.Unstructured
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindJSON(&obj.Object); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	operation, status, err := resourceOperation(c, u.Cluster, u.Kind, dryRun)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	result, err := operation.Create(context.TODO(), q.Namespace, &obj)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(nil, result.Object)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", result.Object)
+}
+
+// PutResource
+// @Summary 更新任意类型资源
+// @accept application/json
+// @Param cluster path string true "Cluster"
+// @Param kind path string true "Kind"
+// @Param name path string true "Name"
+// @Param namespace query string false "Namespace"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/resources/{kind}/{name} [put]
+func PutResource(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u   ResourceUri
+		q   ResourceQuery
+		obj unstructured.Unstructured
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindJSON(&obj.Object); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	operation, status, err := resourceOperation(c, u.Cluster, u.Kind, dryRun)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	var live interface{}
+	if dryRun {
+		live, err = operation.Get(context.TODO(), q.Namespace, u.Name)
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	result, err := operation.Update(context.TODO(), q.Namespace, u.Name, &obj)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(live, result.Object)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", result.Object)
+}
+
+// PatchResource
+// @Summary 按Content-Type以json patch、merge patch、strategic merge patch或apply patch更新任意类型资源，自定义资源不支持strategic merge patch
+// @accept application/json-patch+json
+// @accept application/merge-patch+json
+// @accept application/strategic-merge-patch+json
+// @accept application/apply-patch+yaml
+// @Param cluster path string true "Cluster"
+// @Param kind path string true "Kind"
+// @Param name path string true "Name"
+// @Param namespace query string false "Namespace"
+// @Param fieldManager query string false "FieldManager"
+// @Param force query bool false "Force"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/resources/{kind}/{name} [patch]
+func PatchResource(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u ResourceUri
+		q ResourceQuery
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	patchType, data, opts, status, err := patchRequest(c)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	operation, status, err := resourceOperation(c, u.Cluster, u.Kind, dryRun)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	var live interface{}
+	if dryRun {
+		live, err = operation.Get(context.TODO(), q.Namespace, u.Name)
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	result, err := operation.Patch(context.TODO(), q.Namespace, u.Name, patchType, data, opts)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(live, result.Object)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", result.Object)
+}
+
+// DeleteResource
+// @Summary 删除任意类型资源
+// @Param cluster path string true "Cluster"
+// @Param kind path string true "Kind"
+// @Param name path string true "Name"
+// @Param namespace query string false "Namespace"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/resources/{kind}/{name} [delete]
+func DeleteResource(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u ResourceUri
+		q ResourceQuery
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindQuery(&q); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	operation, status, err := resourceOperation(c, u.Cluster, u.Kind, dryRun)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	var live interface{}
+	if dryRun {
+		live, err = operation.Get(context.TODO(), q.Namespace, u.Name)
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	if err := operation.Delete(context.TODO(), q.Namespace, u.Name); err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(live, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "Deleted Successfully", nil)
+}
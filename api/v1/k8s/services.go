@@ -8,11 +8,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type ServicesQuery struct {
-	Namespace string `form:"namespace"`
+	metadata.NamespaceListParameter
 }
 
 type ServicesUri struct {
@@ -43,21 +44,24 @@ func GetServices(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 	}
 
-	services, err := k8sClient.ClientV1.CoreV1().Services(q.Namespace).List(context.TODO(), metav1.ListOptions{})
-	for i := 0; i < len(services.Items); i++ {
-		services.Items[i].CreationTimestamp = metav1.NewTime(services.Items[i].CreationTimestamp.Add(8 * time.Hour))
-	}
-
+	services, err := k8sClient.ClientV1.CoreV1().Services(q.ListNamespace()).List(context.TODO(), q.ListOptions())
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 		return
 	}
-	appG.Success(http.StatusOK, "ok", services)
+	if q.Output != "" {
+		appG.SuccessTable(services, q.Limit, q.Output)
+		return
+	}
+	for i := 0; i < len(services.Items); i++ {
+		services.Items[i].CreationTimestamp = metav1.NewTime(services.Items[i].CreationTimestamp.Add(8 * time.Hour))
+	}
+	appG.SuccessList(services, q.Limit, services)
 }
 
 func GetService(c *gin.Context) {
@@ -68,7 +72,7 @@ func GetService(c *gin.Context) {
 		return
 	}
 
-	k8sClient, err := k8s.GetClient(u.Cluster)
+	k8sClient, err := k8s.GetClientAs(u.Cluster, app.Impersonation(c))
 	if err != nil {
 		appG.Fail(http.StatusInternalServerError, err, nil)
 	}
@@ -82,3 +86,61 @@ func GetService(c *gin.Context) {
 	}
 	appG.Success(http.StatusOK, "ok", service)
 }
+
+// PatchService
+// @Summary 按Content-Type以json patch、merge patch、strategic merge patch或apply patch更新service
+// @accept application/json-patch+json
+// @accept application/merge-patch+json
+// @accept application/strategic-merge-patch+json
+// @accept application/apply-patch+yaml
+// @Param cluster path string true "Cluster"
+// @Param namespace path string true "Namespace"
+// @Param serviceName path string true "ServiceName"
+// @Param fieldManager query string false "FieldManager"
+// @Param force query bool false "Force"
+// @Param dryRun query bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /k8s/{cluster}/services/{namespace}/{serviceName} [patch]
+func PatchService(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var u ServiceUri
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	patchType, data, opts, status, err := patchRequest(c)
+	if err != nil {
+		appG.Fail(status, err, nil)
+		return
+	}
+	dryRun, err := app.DryRun(c)
+	if err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	k8sClient, err := app.ClientAs(c, u.Cluster, dryRun)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	var live interface{}
+	if dryRun {
+		live, err = k8sClient.ClientV1.CoreV1().Services(u.Namespace).Get(context.TODO(), u.ServiceName, metav1.GetOptions{})
+		if err != nil {
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			return
+		}
+	}
+	service, err := k8sClient.ClientV1.CoreV1().Services(u.Namespace).Patch(context.TODO(), u.ServiceName, patchType, data, opts)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	if dryRun {
+		appG.SuccessDryRun(live, service)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", service)
+}
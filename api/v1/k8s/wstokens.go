@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+)
+
+type WSTokenUri struct {
+	Cluster string `uri:"cluster" binding:"required"`
+}
+
+type WSTokenBody struct {
+	Verb      string `json:"verb" binding:"required,oneof=exec ssh log watch"`
+	Namespace string `json:"namespace" binding:"required_unless=Verb watch"` // watch为空时监听全部namespace
+	Pod       string `json:"pod" binding:"required_unless=Verb watch"`
+	Container string `json:"container" binding:"required_unless=Verb watch"`
+}
+
+type WSToken struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	Target    app.WSTarget `json:"target"`
+}
+
+// PostWSToken
+// @Summary 签发一次性websocket token，通过token参数或Sec-WebSocket-Protocol: wstoken, wstoken.<token>传递
+// @accept application/json
+// @Param cluster path string true "Cluster"
+// @Param RequestBody body WSTokenBody true "RequestBody"
+// @Success 200 {object} app.Response
+// @Failure 403 {object} app.Response
+// @Router /k8s/{cluster}/wstokens [post]
+func PostWSToken(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var (
+		u WSTokenUri
+		b WSTokenBody
+	)
+	if err := appG.C.ShouldBindUri(&u); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := appG.C.ShouldBindJSON(&b); err != nil {
+		appG.Fail(http.StatusBadRequest, err, nil)
+		return
+	}
+	target := app.WSTarget{
+		Cluster:   u.Cluster,
+		Namespace: b.Namespace,
+		Pod:       b.Pod,
+		Container: b.Container,
+		Verb:      b.Verb,
+	}
+	switch b.Verb {
+	case "ssh":
+		target.Verb = app.VerbExec
+	case app.VerbWatch:
+		target.Pod, target.Container = "", ""
+	}
+
+	identity := app.GetIdentity(c)
+	err := app.CheckAuthorized(identity, app.RequestAttributes{
+		Cluster:   target.Cluster,
+		Namespace: target.Namespace,
+		Resource:  "pods",
+		Verb:      target.Verb,
+	})
+	if denied, ok := err.(*app.AuthorizeError); ok {
+		appG.Fail(http.StatusForbidden, denied, denied)
+		return
+	} else if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	token, expiresAt, err := app.IssueWSToken(identity, target)
+	if err != nil {
+		appG.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	appG.Success(http.StatusOK, "ok", WSToken{Token: token, ExpiresAt: expiresAt, Target: target})
+}
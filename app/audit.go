@@ -0,0 +1,124 @@
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+)
+
+// request bodies are truncated to keep audit rows small
+const maxAuditBody = 64 << 10
+
+// bodies of these resources carry credentials and are never recorded
+var auditOmitBody = map[string]bool{
+	"admin/clusters":            true,
+	"admin/testConnectclusters": true,
+	"secrets":                   true,
+	"apply":                     true,
+}
+
+var auditMethods = map[string]bool{
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// Audit records every POST, PUT, PATCH and DELETE with its outcome
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auditMethods[c.Request.Method] {
+			c.Next()
+			return
+		}
+		info := requestInfoOf(c)
+		if !info.ok {
+			c.Next()
+			return
+		}
+		attrs := info.attrs
+		start := time.Now()
+		body := ""
+		// an unresolved resource may be a secret
+		if auditOmitBody[attrs.Resource] || info.err != nil {
+			body = "<omitted>"
+		} else if c.Request.Body != nil {
+			raw, err := ioutil.ReadAll(c.Request.Body)
+			c.Request.Body = ioutil.NopCloser(bytes.NewReader(raw))
+			if err == nil {
+				if len(raw) > maxAuditBody {
+					raw = append(raw[:maxAuditBody:maxAuditBody], "...(truncated)"...)
+				}
+				body = string(raw)
+			}
+		}
+
+		c.Next()
+
+		entry := newAuditEntry(c, models.AuditEventRequest, attrs)
+		entry.RequestBody = body
+		entry.StatusCode = c.Writer.Status()
+		entry.Latency = time.Since(start).Milliseconds()
+		if err := c.Errors.Last(); err != nil {
+			entry.Error = err.Error()
+		}
+		go recordAudit(entry)
+	}
+}
+
+// AuditSession records the start of an interactive session such as a pod
+// shell and returns the func recording its end
+func AuditSession(c *gin.Context) func(err error) {
+	attrs, _ := requestAttributes(c)
+	start := time.Now()
+	sessionID, _ := randomHex(16)
+
+	entry := newAuditEntry(c, models.AuditEventSessionStart, attrs)
+	entry.SessionID = sessionID
+	go recordAudit(entry)
+
+	return func(err error) {
+		entry := newAuditEntry(c, models.AuditEventSessionEnd, attrs)
+		entry.SessionID = sessionID
+		entry.Latency = time.Since(start).Milliseconds()
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		go recordAudit(entry)
+	}
+}
+
+func newAuditEntry(c *gin.Context, event string, attrs RequestAttributes) *models.AuditModel {
+	return &models.AuditModel{Audit: models.Audit{
+		AppKey:    GetIdentity(c).Subject(),
+		SourceIP:  c.ClientIP(),
+		Event:     event,
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Cluster:   attrs.Cluster,
+		Namespace: attrs.Namespace,
+		Resource:  attrs.Resource,
+		Verb:      attrs.Verb,
+	}}
+}
+
+func recordAudit(entry *models.AuditModel) {
+	if err := models.RecordAudit(entry); err != nil && err != models.ErrNoDatabase {
+		log.Printf("audit: failed to record %s %s of %s: %v", entry.Method, entry.Path, entry.AppKey, err)
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
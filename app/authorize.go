@@ -0,0 +1,383 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	VerbGet    = "get"
+	VerbList   = "list"
+	VerbWatch  = "watch"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbPatch  = "patch"
+	VerbDelete = "delete"
+	VerbExec   = "exec"
+	VerbLog    = "log"
+)
+
+// RequestAttributes is what a request does, as checked against caller rules.
+// An empty Namespace means all namespaces.
+type RequestAttributes struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Resource  string `json:"resource"`
+	Verb      string `json:"verb"`
+}
+
+type AuthorizeError struct {
+	Identity Identity          `json:"identity"`
+	Request  RequestAttributes `json:"request"`
+	Rule     *models.RuleModel `json:"rule"` // 命中的deny规则，为空表示没有匹配的allow规则
+}
+
+// route groups subject to authorization
+var authorizedGroups = map[string]bool{"k8s": true, "istio": true, "admin": true}
+
+// resourceAliases maps route segments to the resource they act on
+var resourceAliases = map[string]string{
+	"vs":                "virtualservices",
+	"dr":                "destinationrules",
+	"deployment_status": "deployments",
+	"deployment_pods":   "deployments",
+}
+
+// resources whose handler authorizes every object it acts on, they pass
+// Authorize but are still audited
+var handlerAuthorized = map[string]bool{
+	"apply": true,
+}
+
+// params that address a collection rather than a single object
+var collectionParams = map[string]bool{
+	":cluster":       true,
+	":namespace":     true,
+	":group":         true,
+	":version":       true,
+	":resource":      true,
+	":containerName": true,
+	":kind":          true,
+}
+
+// Authorize checks the request against the rules of the identity set by Auth
+func Authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := requestInfoOf(c)
+		attrs := info.attrs
+		if !info.ok || handlerAuthorized[attrs.Resource] {
+			c.Next()
+			return
+		}
+		appG := Gin{C: c}
+		if info.err != nil {
+			// never authorize a guessed resource name
+			status := http.StatusServiceUnavailable
+			if meta.IsNoMatchError(info.err) {
+				status = http.StatusNotFound
+			}
+			appG.Fail(status, info.err, nil)
+			c.Abort()
+			return
+		}
+		// handlers write to metadata.namespace of the body, it has to be the
+		// namespace that is authorized
+		if namespace := info.bodyNamespace; namespace != "" && attrs.Namespace != "" && namespace != attrs.Namespace {
+			appG.Fail(http.StatusBadRequest, fmt.Errorf("metadata.namespace %q doesn't match namespace %q of the request", namespace, attrs.Namespace), nil)
+			c.Abort()
+			return
+		}
+		if err := CheckAuthorized(GetIdentity(c), attrs); err != nil {
+			if denied, ok := err.(*AuthorizeError); ok {
+				appG.Fail(http.StatusForbidden, denied, denied)
+			} else {
+				appG.Fail(http.StatusInternalServerError, err, nil)
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckAuthorized evaluates the rules of identity for attrs, a denial is
+// returned as *AuthorizeError. Everything is allowed when rbac is disabled.
+func CheckAuthorized(identity Identity, attrs RequestAttributes) error {
+	if !config.RBACEnabled() {
+		return nil
+	}
+	rules, err := identityRules(identity)
+	if err != nil {
+		return err
+	}
+	denied := &AuthorizeError{Identity: identity, Request: attrs}
+	allowed := false
+	for i := range rules {
+		if !ruleMatches(rules[i].Rule, attrs) {
+			continue
+		}
+		if rules[i].Effect == models.RuleDeny {
+			denied.Rule = &rules[i]
+			return denied
+		}
+		allowed = true
+	}
+	if !allowed {
+		return denied
+	}
+	return nil
+}
+
+func (e *AuthorizeError) Error() string {
+	if e.Rule != nil {
+		return fmt.Sprintf("%s is denied to %s %s in cluster %q namespace %q by rule %d",
+			e.Identity, e.Request.Verb, e.Request.Resource, e.Request.Cluster, e.Request.Namespace, e.Rule.ID)
+	}
+	return fmt.Sprintf("%s has no rule allowing to %s %s in cluster %q namespace %q",
+		e.Identity, e.Request.Verb, e.Request.Resource, e.Request.Cluster, e.Request.Namespace)
+}
+
+// ruleMatches reports whether every list of rule has a pattern matching attrs,
+// ssh is accepted as an alias of the exec verb
+func ruleMatches(rule models.Rule, attrs RequestAttributes) bool {
+	verbs := make([]string, len(rule.Verbs))
+	for i, verb := range rule.Verbs {
+		if verb == "ssh" {
+			verb = VerbExec
+		}
+		verbs[i] = verb
+	}
+	return globMatch(rule.Clusters, attrs.Cluster) &&
+		globMatch(rule.Namespaces, attrs.Namespace) &&
+		globMatch(rule.Resources, attrs.Resource) &&
+		globMatch(verbs, attrs.Verb)
+}
+
+func globMatch(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// identityRules loads the rules of every subject of identity. Config rules
+// are caller.<appKey>.rules followed by the rbac.rules of the subject, numbered
+// from 1 in that order.
+func identityRules(identity Identity) ([]models.RuleModel, error) {
+	switch source := config.RBACSource(); source {
+	case config.RBACSourceConfig:
+		var rules, shared []models.Rule
+		if identity.Kind == IdentityCaller {
+			if err := config.UnmarshalKey("caller."+identity.Name+".rules", &rules); err != nil {
+				return nil, err
+			}
+			for i := range rules {
+				rules[i].AppKey = identity.Name
+			}
+		}
+		if err := config.UnmarshalKey("rbac.rules", &shared); err != nil {
+			return nil, err
+		}
+		subjects := identity.Subjects()
+		for _, rule := range shared {
+			for _, subject := range subjects {
+				if rule.AppKey == subject {
+					rules = append(rules, rule)
+					break
+				}
+			}
+		}
+		result := make([]models.RuleModel, len(rules))
+		for i, rule := range rules {
+			result[i].ID = uint(i + 1)
+			result[i].Rule = rule
+		}
+		return result, nil
+	case config.RBACSourceDB:
+		return models.RulesBySubjects(identity.Subjects())
+	default:
+		return nil, fmt.Errorf("unknown rbac source %q", source)
+	}
+}
+
+// contextRequestInfo is the gin context key of the request's *requestInfo
+const contextRequestInfo = "requestInfo"
+
+// requestInfo is what the middlewares learn from a request, computed once
+type requestInfo struct {
+	attrs         RequestAttributes
+	ok            bool
+	bodyNamespace string
+	// err is set when the resource of the request cannot be resolved
+	err error
+}
+
+// requestAttributes derives cluster, namespace, resource and verb from the
+// matched route, false means the route is not subject to authorization
+func requestAttributes(c *gin.Context) (RequestAttributes, bool) {
+	info := requestInfoOf(c)
+	return info.attrs, info.ok
+}
+
+// requestInfoOf returns the cached requestInfo of c, resolving it on first use
+func requestInfoOf(c *gin.Context) *requestInfo {
+	if value, ok := c.Get(contextRequestInfo); ok {
+		return value.(*requestInfo)
+	}
+	info := &requestInfo{}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(c.FullPath(), "/api/v1/"), "/"), "/")
+	if len(segments) >= 2 && authorizedGroups[segments[0]] {
+		info.bodyNamespace = bodyNamespace(c)
+		info.attrs, info.ok, info.err = resolveAttributes(c, segments, info.bodyNamespace)
+	}
+	c.Set(contextRequestInfo, info)
+	return info
+}
+
+func resolveAttributes(c *gin.Context, segments []string, bodyNamespace string) (RequestAttributes, bool, error) {
+	attrs := RequestAttributes{
+		Cluster:   c.Param("cluster"),
+		Namespace: requestNamespace(c, bodyNamespace),
+	}
+
+	if segments[0] == "admin" {
+		attrs.Resource = "admin/" + segments[1]
+		attrs.Verb = methodVerb(c.Request.Method, len(segments) > 2)
+		return attrs, true, nil
+	}
+
+	// k8s and istio routes are /<group>/:cluster/<resource>/...
+	rest := segments[2:]
+	if len(rest) == 0 {
+		return RequestAttributes{}, false, nil
+	}
+	resource, rest := rest[0], rest[1:]
+	switch resource {
+	case "wstokens":
+		// authorized for the websocket the token opens, see CheckAuthorized
+		return RequestAttributes{}, false, nil
+	case "watch":
+		attrs.Resource = rest[0]
+		attrs.Verb = VerbWatch
+		return attrs, true, nil
+	case "crd", "clustercrd":
+		if resource == "clustercrd" {
+			attrs.Namespace = ""
+		}
+		// built-in groups are named like the typed routes
+		resource = k8s.GroupResourceName(c.Param("group"), c.Param("resource"))
+	case "resources":
+		// named like the typed and crd routes so rules cover both
+		mapping, err := k8s.ResourceMapping(attrs.Cluster, c.Param("kind"))
+		if err != nil {
+			return attrs, true, err
+		}
+		resource = k8s.ResourceName(mapping)
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			attrs.Namespace = ""
+		}
+	}
+	if alias, ok := resourceAliases[resource]; ok {
+		resource = alias
+	}
+	attrs.Resource = resource
+
+	named, action := false, ""
+	for _, segment := range rest {
+		if !strings.HasPrefix(segment, ":") {
+			action = segment
+		} else if !collectionParams[segment] {
+			named = true
+		}
+	}
+	switch action {
+	case "ssh":
+		attrs.Verb = VerbExec
+	case "log", "download_log":
+		attrs.Verb = VerbLog
+	case "routes":
+		// routes are part of the virtual service
+		attrs.Verb = VerbGet
+		if c.Request.Method != http.MethodGet {
+			attrs.Verb = VerbUpdate
+		}
+	default:
+		attrs.Verb = methodVerb(c.Request.Method, named)
+	}
+	return attrs, true, nil
+}
+
+func methodVerb(method string, named bool) string {
+	switch method {
+	case http.MethodGet:
+		if named {
+			return VerbGet
+		}
+		return VerbList
+	case http.MethodPost:
+		if named {
+			// actions such as restart and scale
+			return VerbUpdate
+		}
+		return VerbCreate
+	case http.MethodPut:
+		return VerbUpdate
+	case http.MethodPatch:
+		return VerbPatch
+	case http.MethodDelete:
+		return VerbDelete
+	default:
+		return strings.ToLower(method)
+	}
+}
+
+// requestNamespace reads the namespace from the path, the query or the
+// metadata of the body, in that order. ?allNamespaces lists every
+// namespace whatever the namespace query says.
+func requestNamespace(c *gin.Context, bodyNamespace string) string {
+	if namespace := c.Param("namespace"); namespace != "" {
+		return namespace
+	}
+	if allNamespaces, _ := strconv.ParseBool(c.Query("allNamespaces")); allNamespaces {
+		return ""
+	}
+	if namespace := c.Query("namespace"); namespace != "" {
+		return namespace
+	}
+	return bodyNamespace
+}
+
+// bodyNamespace is metadata.namespace of a json or yaml body
+func bodyNamespace(c *gin.Context) string {
+	if c.Request.Body == nil || c.Request.Method == http.MethodGet {
+		return ""
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var object struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	if yaml.Unmarshal(body, &object) != nil {
+		return ""
+	}
+	return object.Metadata.Namespace
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	"github.com/spf13/viper"
+)
+
+func TestRuleMatches(t *testing.T) {
+	attrs := RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods", Verb: VerbExec}
+	allNamespaces := RequestAttributes{Cluster: "prod", Resource: "pods", Verb: VerbExec}
+	tests := []struct {
+		name  string
+		rule  models.Rule
+		attrs RequestAttributes
+		want  bool
+	}{
+		{"exact", models.Rule{Clusters: []string{"prod"}, Namespaces: []string{"team-a"}, Resources: []string{"pods"}, Verbs: []string{"exec"}}, attrs, true},
+		{"globs", models.Rule{Clusters: []string{"*"}, Namespaces: []string{"team-*"}, Resources: []string{"*"}, Verbs: []string{"*"}}, attrs, true},
+		{"ssh alias", models.Rule{Clusters: []string{"prod"}, Namespaces: []string{"team-a"}, Resources: []string{"pods"}, Verbs: []string{"ssh"}}, attrs, true},
+		{"other namespace", models.Rule{Clusters: []string{"prod"}, Namespaces: []string{"team-b"}, Resources: []string{"pods"}, Verbs: []string{"exec"}}, attrs, false},
+		{"other verb", models.Rule{Clusters: []string{"prod"}, Namespaces: []string{"team-a"}, Resources: []string{"pods"}, Verbs: []string{"get"}}, attrs, false},
+		{"empty list", models.Rule{Clusters: []string{"prod"}, Resources: []string{"pods"}, Verbs: []string{"exec"}}, attrs, false},
+		{"namespace glob on all namespaces", models.Rule{Clusters: []string{"prod"}, Namespaces: []string{"team-*"}, Resources: []string{"pods"}, Verbs: []string{"exec"}}, allNamespaces, false},
+		{"wildcard on all namespaces", models.Rule{Clusters: []string{"prod"}, Namespaces: []string{"*"}, Resources: []string{"pods"}, Verbs: []string{"exec"}}, allNamespaces, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleMatches(tt.rule, tt.attrs); got != tt.want {
+				t.Errorf("ruleMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestAttributes(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		route  string
+		url    string
+		body   string
+		want   RequestAttributes
+		ok     bool
+	}{
+		{"list", http.MethodGet, "/api/v1/k8s/:cluster/pods", "/api/v1/k8s/prod/pods?namespace=team-a", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods", Verb: VerbList}, true},
+		{"all namespaces wins over namespace", http.MethodGet, "/api/v1/k8s/:cluster/pods", "/api/v1/k8s/prod/pods?namespace=team-a&allNamespaces=true", "",
+			RequestAttributes{Cluster: "prod", Resource: "pods", Verb: VerbList}, true},
+		{"get", http.MethodGet, "/api/v1/k8s/:cluster/pods/:namespace/:podName", "/api/v1/k8s/prod/pods/team-a/web", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods", Verb: VerbGet}, true},
+		{"exec", http.MethodGet, "/api/v1/k8s/:cluster/pods/:namespace/:podName/ssh", "/api/v1/k8s/prod/pods/team-a/web/ssh", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods", Verb: VerbExec}, true},
+		{"create from body", http.MethodPost, "/api/v1/k8s/:cluster/configmaps", "/api/v1/k8s/prod/configmaps", `{"metadata":{"namespace":"team-a"}}`,
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "configmaps", Verb: VerbCreate}, true},
+		{"create from yaml body", http.MethodPost, "/api/v1/k8s/:cluster/configmaps", "/api/v1/k8s/prod/configmaps", "metadata:\n  namespace: team-a\n",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "configmaps", Verb: VerbCreate}, true},
+		{"action", http.MethodPost, "/api/v1/k8s/:cluster/deployments/:namespace/:deploymentName", "/api/v1/k8s/prod/deployments/team-a/web", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "deployments", Verb: VerbUpdate}, true},
+		{"alias", http.MethodGet, "/api/v1/istio/:cluster/vs", "/api/v1/istio/prod/vs?namespace=team-a", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "virtualservices", Verb: VerbList}, true},
+		{"crd", http.MethodPatch, "/api/v1/k8s/:cluster/crd/:group/:version/:resource/:namespace/:name", "/api/v1/k8s/prod/crd/example.com/v1/widgets/team-a/w", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "widgets.example.com", Verb: VerbPatch}, true},
+		// built-in groups on the crd routes are named like the typed routes
+		{"typed deployment", http.MethodDelete, "/api/v1/k8s/:cluster/deployments/:namespace/:deploymentName", "/api/v1/k8s/prod/deployments/team-a/web", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "deployments", Verb: VerbDelete}, true},
+		{"crd deployment", http.MethodDelete, "/api/v1/k8s/:cluster/crd/:group/:version/:resource/:namespace/:name", "/api/v1/k8s/prod/crd/apps/v1/deployments/team-a/web", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "deployments", Verb: VerbDelete}, true},
+		{"crd in a k8s.io group", http.MethodGet, "/api/v1/k8s/:cluster/crd/:group/:version/:resource", "/api/v1/k8s/prod/crd/rbac.authorization.k8s.io/v1/rolebindings?namespace=team-a", "",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "rolebindings", Verb: VerbList}, true},
+		{"cluster crd", http.MethodPost, "/api/v1/k8s/:cluster/clustercrd/:group/:version/:resource", "/api/v1/k8s/prod/clustercrd/example.com/v1/widgets?namespace=team-a", "",
+			RequestAttributes{Cluster: "prod", Resource: "widgets.example.com", Verb: VerbCreate}, true},
+		{"admin", http.MethodDelete, "/api/v1/admin/clusters/:id", "/api/v1/admin/clusters/1", "",
+			RequestAttributes{Resource: "admin/clusters", Verb: VerbDelete}, true},
+		{"not authorized", http.MethodGet, "/api/v1/health", "/api/v1/health", "", RequestAttributes{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got RequestAttributes
+				ok  bool
+			)
+			r := gin.New()
+			r.Handle(tt.method, tt.route, func(c *gin.Context) {
+				got, ok = requestAttributes(c)
+				// later middlewares get the cached attributes
+				c.Request.Body = http.NoBody
+				if again, _ := requestAttributes(c); again != got {
+					t.Errorf("cached requestAttributes() = %+v, want %+v", again, got)
+				}
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("requestAttributes() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAuthorizeBodyNamespace(t *testing.T) {
+	viper.Set("rbac.enabled", true)
+	viper.Set("rbac.source", "config")
+	viper.Set("caller.team-a.rules", []map[string]interface{}{{
+		"clusters":   []string{"prod"},
+		"namespaces": []string{"team-a"},
+		"resources":  []string{"*"},
+		"verbs":      []string{"*"},
+	}})
+	defer viper.Set("rbac.enabled", false)
+
+	configmaps := "/api/v1/k8s/:cluster/configmaps"
+	widgets := "/api/v1/k8s/:cluster/crd/:group/:version/:resource"
+	tests := []struct {
+		name  string
+		route string
+		url   string
+		body  string
+		want  int
+	}{
+		{"query and body agree", configmaps, "/api/v1/k8s/prod/configmaps?namespace=team-a", `{"metadata":{"namespace":"team-a"}}`, http.StatusOK},
+		{"body only", configmaps, "/api/v1/k8s/prod/configmaps", `{"metadata":{"namespace":"team-a"}}`, http.StatusOK},
+		{"body in another namespace", configmaps, "/api/v1/k8s/prod/configmaps?namespace=team-a", `{"metadata":{"namespace":"kube-system"}}`, http.StatusBadRequest},
+		{"body only in another namespace", configmaps, "/api/v1/k8s/prod/configmaps", `{"metadata":{"namespace":"kube-system"}}`, http.StatusForbidden},
+		{"all namespaces", configmaps, "/api/v1/k8s/prod/configmaps?allNamespaces=true", `{"metadata":{"namespace":"kube-system"}}`, http.StatusForbidden},
+		{"crd", widgets, "/api/v1/k8s/prod/crd/example.com/v1/widgets?namespace=team-a", `{"metadata":{"namespace":"team-a"}}`, http.StatusOK},
+		{"crd in another namespace", widgets, "/api/v1/k8s/prod/crd/example.com/v1/widgets?namespace=team-a", `{"metadata":{"namespace":"kube-system"}}`, http.StatusBadRequest},
+		{"crd body only in another namespace", widgets, "/api/v1/k8s/prod/crd/example.com/v1/widgets", `{"metadata":{"namespace":"kube-system"}}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST(tt.route, func(c *gin.Context) {
+				c.Set(ContextIdentity, Identity{Kind: IdentityCaller, Name: "team-a"})
+			}, Authorize(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body)))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
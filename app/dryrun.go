@@ -0,0 +1,53 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
+)
+
+// DryRun reports whether the request asks to preview its write with ?dryRun=true
+func DryRun(c *gin.Context) (bool, error) {
+	var q metadata.DryRunParameter
+	err := c.ShouldBindQuery(&q)
+	return q.DryRun, err
+}
+
+// ClientAs returns the client of cluster acting for the request, writes
+// through it are not persisted when dryRun is set
+func ClientAs(c *gin.Context, cluster string, dryRun bool) (*k8s.K8sClient, error) {
+	if dryRun {
+		if err := authorizeLiveRead(c); err != nil {
+			return nil, err
+		}
+	}
+	client, err := k8s.GetClientAs(cluster, Impersonation(c))
+	if err != nil || !dryRun {
+		return client, err
+	}
+	return k8s.DryRunClient(client)
+}
+
+// authorizeLiveRead checks that the caller may get the object of the request,
+// dry runs other than creates answer with its live state
+func authorizeLiveRead(c *gin.Context) error {
+	attrs, ok := requestAttributes(c)
+	if !ok || handlerAuthorized[attrs.Resource] || attrs.Verb == VerbCreate {
+		return nil
+	}
+	attrs.Verb = VerbGet
+	return CheckAuthorized(GetIdentity(c), attrs)
+}
+
+// SuccessDryRun answers a dry run with what it would have written and its
+// diff against live, a nil live is a create and a nil result a delete
+func (g *Gin) SuccessDryRun(live, result interface{}) {
+	dryRunResult, err := k8s.NewDryRunResult(live, result)
+	if err != nil {
+		g.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	g.Success(http.StatusOK, "dry run", dryRunResult)
+}
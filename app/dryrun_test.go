@@ -0,0 +1,54 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func TestAuthorizeLiveRead(t *testing.T) {
+	viper.Set("rbac.enabled", true)
+	viper.Set("rbac.source", "config")
+	viper.Set("caller.team-a.rules", []map[string]interface{}{{
+		"clusters":   []string{"prod"},
+		"namespaces": []string{"team-a"},
+		"resources":  []string{"configmaps"},
+		"verbs":      []string{"create", "delete"},
+	}, {
+		"clusters":   []string{"prod"},
+		"namespaces": []string{"team-a"},
+		"resources":  []string{"deployments"},
+		"verbs":      []string{"get", "delete"},
+	}})
+	defer viper.Set("rbac.enabled", false)
+
+	tests := []struct {
+		name    string
+		method  string
+		route   string
+		url     string
+		allowed bool
+	}{
+		{"delete without get", http.MethodDelete, "/api/v1/k8s/:cluster/configmaps/:namespace/:name", "/api/v1/k8s/prod/configmaps/team-a/app", false},
+		{"delete with get", http.MethodDelete, "/api/v1/k8s/:cluster/deployments/:namespace/:deploymentName", "/api/v1/k8s/prod/deployments/team-a/web", true},
+		{"create reads nothing", http.MethodPost, "/api/v1/k8s/:cluster/configmaps", "/api/v1/k8s/prod/configmaps?namespace=team-a", true},
+		{"apply authorizes each object", http.MethodPost, "/api/v1/k8s/:cluster/apply", "/api/v1/k8s/prod/apply", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			r := gin.New()
+			r.Handle(tt.method, tt.route, func(c *gin.Context) {
+				c.Set(ContextIdentity, Identity{Kind: IdentityCaller, Name: "team-a"})
+				err = authorizeLiveRead(c)
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.url, http.NoBody))
+			if _, denied := err.(*AuthorizeError); err != nil && !denied || (err == nil) != tt.allowed {
+				t.Errorf("authorizeLiveRead() = %v, want allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
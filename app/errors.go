@@ -0,0 +1,78 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// APIError is the structured form of an error from a cluster, clients
+// branch on its reason
+type APIError struct {
+	Reason  metav1.StatusReason   `json:"reason"`
+	Message string                `json:"message"`
+	Details *metav1.StatusDetails `json:"details,omitempty"` // 资源的name、group、kind及retryAfterSeconds
+	Causes  []metav1.StatusCause  `json:"causes,omitempty"`  // 字段级别的错误，如校验失败
+	Cluster string                `json:"cluster,omitempty"`
+}
+
+// status codes of api server errors the caller can act on, other reasons
+// keep the status the handler chose
+var apiStatusCodes = map[metav1.StatusReason]int{
+	metav1.StatusReasonBadRequest:            http.StatusBadRequest,
+	metav1.StatusReasonForbidden:             http.StatusForbidden,
+	metav1.StatusReasonNotFound:              http.StatusNotFound,
+	metav1.StatusReasonMethodNotAllowed:      http.StatusMethodNotAllowed,
+	metav1.StatusReasonNotAcceptable:         http.StatusNotAcceptable,
+	metav1.StatusReasonAlreadyExists:         http.StatusConflict,
+	metav1.StatusReasonConflict:              http.StatusConflict,
+	metav1.StatusReasonGone:                  http.StatusGone,
+	metav1.StatusReasonExpired:               http.StatusGone,
+	metav1.StatusReasonRequestEntityTooLarge: http.StatusRequestEntityTooLarge,
+	metav1.StatusReasonUnsupportedMediaType:  http.StatusUnsupportedMediaType,
+	metav1.StatusReasonInvalid:               http.StatusUnprocessableEntity,
+	metav1.StatusReasonTooManyRequests:       http.StatusTooManyRequests,
+}
+
+// apiError unwraps err to an api server status, an unhealthy cluster or a
+// refused impersonation and returns its structured form with the status code
+// to answer with
+func (g *Gin) apiError(httpCode int, err error) (*APIError, int) {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		s := status.Status()
+		apiErr := &APIError{
+			Reason:  s.Reason,
+			Message: s.Message,
+			Cluster: g.C.Param("cluster"),
+		}
+		if s.Details != nil {
+			details := *s.Details
+			apiErr.Causes = details.Causes
+			details.Causes = nil
+			apiErr.Details = &details
+		}
+		if code, ok := apiStatusCodes[s.Reason]; ok {
+			httpCode = code
+		}
+		return apiErr, httpCode
+	}
+	if errors.Is(err, k8s.ErrClusterUnhealthy) {
+		return &APIError{
+			Reason:  metav1.StatusReasonServiceUnavailable,
+			Message: err.Error(),
+			Cluster: g.C.Param("cluster"),
+		}, http.StatusServiceUnavailable
+	}
+	if errors.Is(err, k8s.ErrSystemImpersonation) {
+		return &APIError{
+			Reason:  metav1.StatusReasonForbidden,
+			Message: err.Error(),
+			Cluster: g.C.Param("cluster"),
+		}, http.StatusForbidden
+	}
+	return nil, httpCode
+}
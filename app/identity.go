@@ -0,0 +1,74 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"k8s.io/client-go/rest"
+)
+
+// ContextIdentity is the gin context key of the authenticated Identity
+const ContextIdentity = "identity"
+
+const (
+	IdentityCaller = "caller"
+	IdentityUser   = "user"
+)
+
+// Identity is who sent a request, an appKey caller or a token user
+type Identity struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// Subject is the primary rule subject, the appKey of callers and
+// user:<name> of token users
+func (i Identity) Subject() string {
+	if i.Kind == IdentityUser {
+		return "user:" + i.Name
+	}
+	return i.Name
+}
+
+// Subjects are all rule subjects of the identity, groups are group:<name>
+func (i Identity) Subjects() []string {
+	subjects := []string{i.Subject()}
+	for _, group := range i.Groups {
+		subjects = append(subjects, "group:"+group)
+	}
+	return subjects
+}
+
+func (i Identity) String() string {
+	if len(i.Groups) == 0 {
+		return i.Subject()
+	}
+	return i.Subject() + " (groups " + strings.Join(i.Groups, ",") + ")"
+}
+
+// GetIdentity returns the identity set by Auth
+func GetIdentity(c *gin.Context) Identity {
+	if identity, ok := c.Get(ContextIdentity); ok {
+		return identity.(Identity)
+	}
+	return Identity{}
+}
+
+// Impersonation is the kubernetes user and groups the request acts as on
+// clusters with impersonation enabled
+func Impersonation(c *gin.Context) rest.ImpersonationConfig {
+	identity := GetIdentity(c)
+	if identity.Name == "" {
+		return rest.ImpersonationConfig{}
+	}
+	if identity.Kind == IdentityCaller {
+		return rest.ImpersonationConfig{UserName: config.ImpersonationCallerPrefix() + identity.Name}
+	}
+	groups := make([]string, len(identity.Groups))
+	for i, group := range identity.Groups {
+		groups[i] = config.ImpersonationGroupPrefix() + group
+	}
+	return rest.ImpersonationConfig{UserName: config.ImpersonationUserPrefix() + identity.Name, Groups: groups}
+}
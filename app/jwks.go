@@ -0,0 +1,233 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+)
+
+const (
+	jwksFetchTimeout = 10 * time.Second
+	// unknown key ids trigger a reload, but not more often than this
+	jwksMinReload = 30 * time.Second
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksCache holds the verification keys of the configured JWKS by key id.
+// Key sets are fetched without holding mu, lookups of known keys never wait
+// for a fetch.
+type jwksCache struct {
+	mu         sync.RWMutex
+	source     string
+	keys       map[string]interface{}
+	loadedAt   time.Time
+	triedAt    time.Time // last fetch, failed or not
+	refreshing bool
+	// loading serializes fetches
+	loading sync.Mutex
+}
+
+var jwks = &jwksCache{}
+
+// Key returns the public key of kid, a token without kid may use the only key of the set
+func (j *jwksCache) Key(kid string) (interface{}, error) {
+	source := config.OIDCJWKSFile()
+	if source == "" {
+		source = config.OIDCJWKSURL()
+	}
+	if source == "" {
+		return nil, errors.New("oidc.jwksFile or oidc.jwksURL is not configured")
+	}
+
+	j.mu.Lock()
+	key, ok := j.lookup(kid)
+	loadedAt, current := j.loadedAt, source == j.source
+	throttled := time.Since(j.triedAt) <= jwksMinReload
+	stale := time.Since(loadedAt) > config.OIDCJWKSRefresh()
+	refresh := ok && current && stale && !throttled && !j.refreshing
+	if refresh {
+		j.refreshing, j.triedAt = true, time.Now()
+	}
+	j.mu.Unlock()
+	switch {
+	case ok && current:
+		if refresh {
+			// the cached key stays valid while the set refreshes
+			go func() {
+				if err := j.reload(source, loadedAt); err != nil {
+					log.Printf("failed to refresh jwks: %v", err)
+				}
+			}()
+		}
+		return key, nil
+	case current && throttled:
+		return nil, fmt.Errorf("no jwks key with kid %q", kid)
+	}
+
+	if err := j.reload(source, loadedAt); err != nil {
+		return nil, fmt.Errorf("failed to load jwks: %v", err)
+	}
+	j.mu.RLock()
+	key, ok = j.lookup(kid)
+	j.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no jwks key with kid %q", kid)
+}
+
+// reload fetches the key set of source and swaps it in, unless another
+// caller already did since loadedAt
+func (j *jwksCache) reload(source string, loadedAt time.Time) error {
+	j.loading.Lock()
+	defer j.loading.Unlock()
+	defer func() {
+		j.mu.Lock()
+		j.refreshing = false
+		j.mu.Unlock()
+	}()
+
+	j.mu.RLock()
+	reloaded := source == j.source && j.loadedAt.After(loadedAt)
+	j.mu.RUnlock()
+	if reloaded {
+		return nil
+	}
+	j.mu.Lock()
+	j.triedAt = time.Now()
+	j.mu.Unlock()
+	keys, err := loadJWKS(source)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.source, j.keys, j.loadedAt = source, keys, time.Now()
+	j.mu.Unlock()
+	return nil
+}
+
+func (j *jwksCache) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// loadJWKS reads a key set from a file path or an http(s) url
+func loadJWKS(source string) (map[string]interface{}, error) {
+	var (
+		raw []byte
+		err error
+	)
+	if source == config.OIDCJWKSFile() {
+		raw, err = ioutil.ReadFile(source)
+	} else {
+		raw, err = fetchJWKS(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func fetchJWKS(url string) ([]byte, error) {
+	client := http.Client{Timeout: jwksFetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
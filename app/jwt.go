@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+)
+
+var jwtParser = jwt.NewParser(jwt.WithValidMethods([]string{
+	"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512",
+}))
+
+// verifyBearerToken validates a jwt against the configured issuer and jwks
+// and maps its claims to an Identity
+func verifyBearerToken(raw string) (Identity, error) {
+	issuer := config.OIDCIssuer()
+	if issuer == "" {
+		return Identity{}, errors.New("oidc.issuer is not configured")
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwtParser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return jwks.Key(kid)
+	})
+	if err != nil {
+		return Identity{}, err
+	}
+	if _, ok := claims["exp"]; !ok {
+		return Identity{}, errors.New("token has no exp claim")
+	}
+	if !claims.VerifyIssuer(issuer, true) {
+		return Identity{}, fmt.Errorf("token issuer is not %s", issuer)
+	}
+	if audience := config.OIDCAudience(); audience != "" && !claims.VerifyAudience(audience, true) {
+		return Identity{}, fmt.Errorf("token audience does not contain %s", audience)
+	}
+
+	usernameClaim := config.OIDCUsernameClaim()
+	name, _ := claims[usernameClaim].(string)
+	if name == "" {
+		return Identity{}, fmt.Errorf("token has no %s claim", usernameClaim)
+	}
+	identity := Identity{Kind: IdentityUser, Name: name}
+	switch groups := claims[config.OIDCGroupsClaim()].(type) {
+	case string:
+		identity.Groups = []string{groups}
+	case []interface{}:
+		for _, group := range groups {
+			if group, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, group)
+			}
+		}
+	}
+	return identity, nil
+}
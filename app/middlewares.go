@@ -1,14 +1,21 @@
 package app
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	"github.com/mizhexiaoxiao/k8s-api-service/utils"
+	"gorm.io/gorm"
 )
 
 //简单校验
@@ -20,20 +27,152 @@ func Auth() gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		if token := wsTokenFromRequest(c); token != "" {
+			identity, err := consumeWSToken(c, token)
+			if err != nil {
+				appG.Fail(http.StatusUnauthorized, err, nil)
+				c.Abort()
+				return
+			}
+			c.Set(ContextIdentity, identity)
+			c.Next()
+			return
+		}
+		if authorization := appG.C.GetHeader("Authorization"); config.OIDCEnabled() && strings.HasPrefix(authorization, "Bearer ") {
+			identity, err := verifyBearerToken(strings.TrimPrefix(authorization, "Bearer "))
+			if err != nil {
+				appG.Fail(http.StatusUnauthorized, err, nil)
+				c.Abort()
+				return
+			}
+			c.Set(ContextIdentity, identity)
+			c.Next()
+			return
+		}
 		appKey := appG.C.GetHeader("appKey")
-		appSecret := appG.C.GetHeader("appSecret")
-		configSecret := config.GetString("caller" + "." + appKey + ".secret")
-		if configSecret == "" {
-			appG.Fail(http.StatusInternalServerError, errors.New(fmt.Sprintf("Please carry appKey and appSecret in the request header")), nil)
-			log.Println(errors.New(fmt.Sprintf("Please carry appKey and appSecret in the request header")))
+		if appKey != "" && !config.ValidAppKey(appKey) {
+			appG.Fail(http.StatusUnauthorized, errors.New("Authentication failed"), nil)
 			c.Abort()
 			return
 		}
-		if appSecret != configSecret {
-			appG.Fail(http.StatusUnauthorized, errors.New("Authentication failed"), nil)
+		caller, err := models.CallerByAppKey(appKey)
+		switch {
+		case err == nil:
+			err = verifyStoredCaller(c, &caller)
+		case err == models.ErrNoDatabase || errors.Is(err, gorm.ErrRecordNotFound):
+			// callers of the config file are the bootstrap fallback
+			configSecret := config.CallerSecret(appKey)
+			if configSecret == "" {
+				appG.Fail(http.StatusInternalServerError, errors.New(fmt.Sprintf("Please carry appKey and appSecret in the request header")), nil)
+				log.Println(errors.New(fmt.Sprintf("Please carry appKey and appSecret in the request header")))
+				c.Abort()
+				return
+			}
+			err = verifyConfigCaller(c, appKey, configSecret)
+		default:
+			appG.Fail(http.StatusInternalServerError, err, nil)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			appG.Fail(http.StatusUnauthorized, err, nil)
 			c.Abort()
 			return
 		}
+		c.Set(ContextIdentity, Identity{Kind: IdentityCaller, Name: appKey})
 		c.Next()
 	}
 }
+
+func verifyConfigCaller(c *gin.Context, appKey, secret string) error {
+	switch mode := config.CallerMode(appKey); mode {
+	case config.CallerModeHeader:
+		if c.GetHeader("appSecret") != secret {
+			return errors.New("Authentication failed")
+		}
+		return nil
+	case config.CallerModeHMAC:
+		return verifySignedRequest(c, appKey, secret)
+	default:
+		return fmt.Errorf("unknown auth mode %q of caller %s", mode, appKey)
+	}
+}
+
+// verifyStoredCaller checks a database caller like verifyConfigCaller and
+// records when it was last used, at most once a minute
+func verifyStoredCaller(c *gin.Context, caller *models.CallerModel) error {
+	now := time.Now()
+	switch mode := caller.Mode; mode {
+	case "", config.CallerModeHeader:
+		ok, err := caller.Verify(c.GetHeader("appSecret"), now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("Authentication failed")
+		}
+	case config.CallerModeHMAC:
+		secrets, err := caller.SigningSecrets(now)
+		if err != nil {
+			return err
+		}
+		if err := verifySignedRequest(c, caller.AppKey, secrets...); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown auth mode %q of caller %s", mode, caller.AppKey)
+	}
+	if caller.LastUsedAt == nil || now.Sub(*caller.LastUsedAt) > time.Minute {
+		go func(id uint) {
+			if err := models.TouchCaller(id, now); err != nil {
+				log.Printf("failed to update last used time of caller %d: %v", id, err)
+			}
+		}(caller.ID)
+	}
+	return nil
+}
+
+// verifySignedRequest checks the timestamp, nonce and signature headers of a
+// request signed with utils.CreateSign and any of secrets
+func verifySignedRequest(c *gin.Context, appKey string, secrets ...string) error {
+	timestamp := c.GetHeader("timestamp")
+	nonce := c.GetHeader("nonce")
+	signature := c.GetHeader("signature")
+	if timestamp == "" || nonce == "" || signature == "" {
+		return errors.New("Please carry appKey, timestamp, nonce and signature in the request header")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("timestamp must be unix seconds")
+	}
+	maxSkew := config.SignMaxSkew()
+	signedAt := time.Unix(ts, 0)
+	if skew := time.Since(signedAt); skew > maxSkew || skew < -maxSkew {
+		return errors.New("timestamp is out of the accepted window")
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		if body, err = ioutil.ReadAll(c.Request.Body); err != nil {
+			return err
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	signed := false
+	for _, secret := range secrets {
+		expected := utils.CreateSign(secret, c.Request.Method, c.Request.URL.Path, c.Request.URL.Query(), timestamp, nonce, body)
+		if utils.VerifySign(signature, expected) {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return errors.New("Authentication failed")
+	}
+	// only valid signatures use up a nonce, so forged requests can't burn one
+	if !nonces.Use(appKey, nonce, signedAt.Add(maxSkew)) {
+		return errors.New("nonce has already been used")
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	"github.com/mizhexiaoxiao/k8s-api-service/utils"
+	"github.com/spf13/viper"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// signedRequest signs body and query like a client and sends sentBody
+func signedRequest(secret, nonce string, signedAt time.Time, query url.Values, rawQuery, body, sentBody string) *gin.Context {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	path := "/api/v1/k8s/prod/configmaps"
+	req := httptest.NewRequest(http.MethodPost, path+"?"+rawQuery, strings.NewReader(sentBody))
+	req.Header.Set("timestamp", timestamp)
+	req.Header.Set("nonce", nonce)
+	req.Header.Set("signature", utils.CreateSign(secret, http.MethodPost, path, query, timestamp, nonce, []byte(body)))
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	return c
+}
+
+func TestVerifySignedRequest(t *testing.T) {
+	const secret = "s3cret"
+	body := `{"metadata":{"name":"app"}}`
+	query := url.Values{"namespace": {"team-a"}, "label": {"b", "a"}}
+	now := time.Now()
+	tests := []struct {
+		name     string
+		nonce    string
+		signedAt time.Time
+		rawQuery string
+		sentBody string
+		secret   string
+		ok       bool
+	}{
+		{"valid", "n-valid", now, "namespace=team-a&label=b&label=a", body, secret, true},
+		{"query order does not matter", "n-order", now, "label=a&namespace=team-a&label=b", body, secret, true},
+		{"tampered body", "n-body", now, "namespace=team-a&label=b&label=a", `{"metadata":{"name":"other"}}`, secret, false},
+		{"tampered query", "n-query", now, "namespace=kube-system&label=b&label=a", body, secret, false},
+		{"wrong secret", "n-secret", now, "namespace=team-a&label=b&label=a", body, "other", false},
+		{"stale timestamp", "n-stale", now.Add(-time.Hour), "namespace=team-a&label=b&label=a", body, secret, false},
+		{"future timestamp", "n-future", now.Add(time.Hour), "namespace=team-a&label=b&label=a", body, secret, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := signedRequest(secret, tt.nonce, tt.signedAt, query, tt.rawQuery, body, tt.sentBody)
+			if err := verifySignedRequest(c, "team-a", tt.secret); (err == nil) != tt.ok {
+				t.Errorf("verifySignedRequest() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestVerifySignedRequestReplay(t *testing.T) {
+	const secret = "s3cret"
+	query := url.Values{"namespace": {"team-a"}}
+	send := func(nonce string) error {
+		c := signedRequest(secret, nonce, time.Now(), query, query.Encode(), "{}", "{}")
+		return verifySignedRequest(c, "team-a", secret)
+	}
+	if err := send("n-replay"); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if err := send("n-replay"); err == nil {
+		t.Error("replayed nonce was accepted")
+	}
+	// a forged request must not use up the nonce of a later valid one
+	forged := signedRequest("other", "n-forged", time.Now(), query, query.Encode(), "{}", "{}")
+	if err := verifySignedRequest(forged, "team-a", secret); err == nil {
+		t.Fatal("forged request was accepted")
+	}
+	if err := send("n-forged"); err != nil {
+		t.Errorf("nonce of a forged request was used up: %v", err)
+	}
+}
+
+func TestAuthStoredSigningCaller(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.CallerModel{}); err != nil {
+		t.Fatal(err)
+	}
+	models.DB = db
+	defer func() { models.DB = nil }()
+	viper.Set("crypto.keyVersion", 1)
+	viper.Set("crypto.keys", map[string]string{"1": "passphrase"})
+	defer viper.Set("crypto.keyVersion", 0)
+
+	caller := models.CallerModel{Caller: models.Caller{AppKey: "signer", Mode: config.CallerModeHMAC, Enabled: true}}
+	previous, err := caller.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rotated, the previous secret signs during the grace period
+	gracePeriod := time.Now().Add(time.Hour)
+	caller.PreviousSecretSealed, caller.PreviousExpiresAt = caller.SecretSealed, &gracePeriod
+	secret, err := caller.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&caller).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.Use(Auth())
+	r.POST("/api/v1/k8s/:cluster/configmaps", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	query := url.Values{"namespace": {"team-a"}}
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		want    int
+	}{
+		{"signed", func() *http.Request {
+			return signedRequest(secret, "n-stored", time.Now(), query, query.Encode(), "{}", "{}").Request
+		}, http.StatusOK},
+		{"signed with the previous secret", func() *http.Request {
+			return signedRequest(previous, "n-stored-previous", time.Now(), query, query.Encode(), "{}", "{}").Request
+		}, http.StatusOK},
+		{"replayed", func() *http.Request {
+			return signedRequest(secret, "n-stored", time.Now(), query, query.Encode(), "{}", "{}").Request
+		}, http.StatusUnauthorized},
+		{"secret header", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/k8s/prod/configmaps?"+query.Encode(), strings.NewReader("{}"))
+			req.Header.Set("appSecret", secret)
+			return req
+		}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.request()
+			req.Header.Set("appKey", "signer")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthRejectsSubjectAppKey(t *testing.T) {
+	// a caller named like a token user would match that user's rules
+	viper.Set("caller.user:alice.secret", "s3cret")
+	defer viper.Set("caller.user:alice.secret", "")
+
+	r := gin.New()
+	r.Use(Auth())
+	r.GET("/api/v1/k8s/:cluster/pods", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/k8s/prod/pods", nil)
+	req.Header.Set("appKey", "user:alice")
+	req.Header.Set("appSecret", "s3cret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
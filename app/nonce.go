@@ -0,0 +1,39 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+const noncePruneInterval = time.Minute
+
+// nonceCache remembers the nonces of signed requests until they expire
+type nonceCache struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	pruned time.Time
+}
+
+var nonces = &nonceCache{seen: make(map[string]time.Time)}
+
+// Use records the nonce of appKey until expireAt, false means it was already used
+func (n *nonceCache) Use(appKey, nonce string, expireAt time.Time) bool {
+	now := time.Now()
+	key := appKey + "/" + nonce
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if now.Sub(n.pruned) > noncePruneInterval {
+		for k, t := range n.seen {
+			if now.After(t) {
+				delete(n.seen, k)
+			}
+		}
+		n.pruned = now
+	}
+	if t, ok := n.seen[key]; ok && now.Before(t) {
+		return false
+	}
+	n.seen[key] = expireAt
+	return true
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
+)
+
+// ExpectedResourceVersion is the resourceVersion a write must still find,
+// taken from ?resourceVersion= or an If-Match header, empty when neither is sent
+func ExpectedResourceVersion(c *gin.Context) (string, error) {
+	var q metadata.PreconditionParameter
+	if err := c.ShouldBindQuery(&q); err != nil {
+		return "", err
+	}
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return q.ResourceVersion, nil
+	}
+	ifMatch = strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	if q.ResourceVersion != "" && q.ResourceVersion != ifMatch {
+		return "", fmt.Errorf("resourceVersion %s does not match If-Match %s", q.ResourceVersion, ifMatch)
+	}
+	return ifMatch, nil
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"golang.org/x/time/rate"
+)
+
+const (
+	RateScopeCaller  = "caller"
+	RateScopeCluster = "cluster"
+)
+
+// concurrency caps can't tell when a session frees up
+const wsRetryAfter = 5 * time.Second
+
+// how often idle buckets are dropped
+const limiterPruneInterval = time.Minute
+
+type limiterEntry struct {
+	limit    config.RateLimit
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// full reports whether the bucket has refilled since its last use, it then
+// behaves like a new one and can be dropped
+func (e *limiterEntry) full(now time.Time) bool {
+	refill := time.Duration(float64(e.limit.Burst) / e.limit.Rate * float64(time.Second))
+	return now.Sub(e.lastUsed) >= refill
+}
+
+// rateLimiters holds the buckets of recently seen keys and the session
+// counts of open websockets, a count is deleted when it drops to zero
+type rateLimiters struct {
+	mu         sync.Mutex
+	limiters   map[string]*limiterEntry
+	sessions   map[string]int
+	lastPruned time.Time
+}
+
+var limiters = &rateLimiters{
+	limiters: make(map[string]*limiterEntry),
+	sessions: make(map[string]int),
+}
+
+// RateLimit throttles requests with token buckets per caller and per cluster
+// of each route group and caps concurrent websocket sessions
+func RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.RateLimitEnabled() {
+			c.Next()
+			return
+		}
+		group := routeGroup(c)
+		if group == "" {
+			c.Next()
+			return
+		}
+		keys := map[string]string{RateScopeCaller: GetIdentity(c).Subject()}
+		if cluster := c.Param("cluster"); cluster != "" {
+			keys[RateScopeCluster] = cluster
+		}
+
+		if delay, scope := limiters.reserve(group, keys); delay > 0 {
+			tooManyRequests(c, delay, fmt.Errorf("rate limit of %s %s exceeded", scope, keys[scope]))
+			return
+		}
+		if websocket.IsWebSocketUpgrade(c.Request) {
+			release, scope := limiters.acquireSession(keys)
+			if release == nil {
+				tooManyRequests(c, wsRetryAfter, fmt.Errorf("too many websocket sessions of %s %s", scope, keys[scope]))
+				return
+			}
+			defer release()
+		}
+		c.Next()
+	}
+}
+
+// reserve takes a token from every bucket of keys, or none of them and
+// returns how long to wait for the scope that ran out
+func (l *rateLimiters) reserve(group string, keys map[string]string) (time.Duration, string) {
+	now := time.Now()
+	var reserved []*rate.Reservation
+	for _, scope := range []string{RateScopeCaller, RateScopeCluster} {
+		key, ok := keys[scope]
+		if !ok {
+			continue
+		}
+		limiter := l.limiter(group, scope, key)
+		if limiter == nil {
+			continue
+		}
+		r := limiter.ReserveN(now, 1)
+		delay := r.DelayFrom(now)
+		if !r.OK() {
+			delay = time.Duration(math.MaxInt64)
+		}
+		if delay > 0 {
+			r.CancelAt(now)
+			for _, other := range reserved {
+				other.CancelAt(now)
+			}
+			return delay, scope
+		}
+		reserved = append(reserved, r)
+	}
+	return 0, ""
+}
+
+// limiter returns the bucket of key, rebuilt when its configured limit
+// changed, nil when unlimited
+func (l *rateLimiters) limiter(group, scope, key string) *rate.Limiter {
+	limit := config.RateLimitFor(group, scope)
+	if limit.Rate <= 0 {
+		return nil
+	}
+	name := group + "/" + scope + "/" + key
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastPruned) >= limiterPruneInterval {
+		for name, entry := range l.limiters {
+			if entry.full(now) {
+				delete(l.limiters, name)
+			}
+		}
+		l.lastPruned = now
+	}
+	entry, ok := l.limiters[name]
+	if !ok || entry.limit != limit {
+		entry = &limiterEntry{limit: limit, limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)}
+		l.limiters[name] = entry
+	}
+	entry.lastUsed = now
+	return entry.limiter
+}
+
+// acquireSession counts a websocket session against the caps of keys,
+// release is nil when a cap is reached
+func (l *rateLimiters) acquireSession(keys map[string]string) (release func(), scope string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for scope, key := range keys {
+		if max := config.WSMaxSessions(scope); max > 0 && l.sessions[scope+"/"+key] >= max {
+			return nil, scope
+		}
+	}
+	for scope, key := range keys {
+		l.sessions[scope+"/"+key]++
+	}
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for scope, key := range keys {
+			if l.sessions[scope+"/"+key]--; l.sessions[scope+"/"+key] <= 0 {
+				delete(l.sessions, scope+"/"+key)
+			}
+		}
+	}, ""
+}
+
+func tooManyRequests(c *gin.Context, retryAfter time.Duration, err error) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if retryAfter == time.Duration(math.MaxInt64) {
+		// the burst is too small to ever allow a request
+		seconds = 0
+	}
+	if seconds > 0 {
+		c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+	appG := Gin{C: c}
+	appG.Fail(http.StatusTooManyRequests, err, nil)
+	c.Abort()
+}
+
+// routeGroup is the route group (k8s, istio, admin) of the matched route
+func routeGroup(c *gin.Context) string {
+	segments := strings.Split(strings.TrimPrefix(c.FullPath(), "/api/v1/"), "/")
+	if len(segments) < 2 || !authorizedGroups[segments[0]] {
+		return ""
+	}
+	return segments[0]
+}
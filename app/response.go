@@ -1,10 +1,14 @@
 package app
 
 import (
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 type Gin struct {
@@ -15,17 +19,25 @@ type Response struct {
 	Code int         `json:"code"`
 	Msg  string      `json:"msg"`
 	Data interface{} `json:"data"`
+	// 集群返回的错误
+	Error *APIError `json:"error,omitempty"`
 }
 
 type ResponseExtra struct {
 	Total    int64 `json:"total"`
 	Page     int   `json:"page"`
 	PageSize int   `json:"pageSize"`
+	// 集群列表下一页的continue，为空时已是最后一页
+	Continue string `json:"continue,omitempty"`
 	Response
 }
 
-// Response setting gin.JSON
+// Response setting gin.JSON, GET requests accepting yaml get the bare data
 func (g *Gin) Success(httpCode int, msg string, data interface{}) {
+	if wantsYAML(g.C) {
+		g.writeYAML(httpCode, data)
+		return
+	}
 	g.C.JSON(httpCode, Response{
 		Code: httpCode,
 		Msg:  msg,
@@ -34,6 +46,10 @@ func (g *Gin) Success(httpCode int, msg string, data interface{}) {
 }
 
 func (g *Gin) SuccessExtra(total int64, page int, pageSize, httpCode int, msg string, data interface{}) {
+	if wantsYAML(g.C) {
+		g.writeYAML(httpCode, data)
+		return
+	}
 	g.C.JSON(httpCode, ResponseExtra{
 		Total:    total,
 		Page:     page,
@@ -45,7 +61,61 @@ func (g *Gin) SuccessExtra(total int64, page int, pageSize, httpCode int, msg st
 	})
 }
 
+// SuccessList answers a page of a cluster list. Total counts this page and,
+// when the api server reports them, the items after it.
+func (g *Gin) SuccessList(list runtime.Object, limit int64, data interface{}) {
+	if wantsYAML(g.C) {
+		g.writeYAML(http.StatusOK, data)
+		return
+	}
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		g.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	total := int64(meta.LenList(list))
+	if remaining := listMeta.GetRemainingItemCount(); remaining != nil {
+		total += *remaining
+	}
+	g.C.JSON(http.StatusOK, ResponseExtra{
+		Total:    total,
+		PageSize: int(limit),
+		Continue: listMeta.GetContinue(),
+		Response: Response{
+			Code: http.StatusOK,
+			Msg:  "ok",
+			Data: data},
+	})
+}
+
+// SuccessTable answers a page of a cluster list as the table kubectl get
+// prints, output wide adds the wide columns
+func (g *Gin) SuccessTable(list runtime.Object, limit int64, output string) {
+	table, err := k8s.PrintTable(list, output == k8s.OutputWide)
+	if err != nil {
+		g.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	g.SuccessList(list, limit, table)
+}
+
 func (g *Gin) Fail(httpCode int, err error, data interface{}) {
+	// keep the error on the context for the audit log
+	_ = g.C.Error(err)
+	// denials answer 403 whatever status the handler chose
+	if denied, ok := err.(*AuthorizeError); ok {
+		httpCode, data = http.StatusForbidden, denied
+	}
+	// 集群返回的错误按reason映射状态码
+	if apiErr, code := g.apiError(httpCode, err); apiErr != nil {
+		g.C.JSON(code, Response{
+			Code:  code,
+			Msg:   err.Error(),
+			Data:  data,
+			Error: apiErr,
+		})
+		return
+	}
 	errs, ok := err.(validator.ValidationErrors)
 	if !ok {
 		// 非validator.ValidationErrors类型错误直接返回
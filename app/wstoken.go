@@ -0,0 +1,131 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+)
+
+const (
+	// WSTokenProtocol is offered by browsers next to wstoken.<token> and
+	// selected by the upgrader
+	WSTokenProtocol       = "wstoken"
+	wsTokenProtocolPrefix = WSTokenProtocol + "."
+)
+
+var ErrInvalidWSToken = errors.New("websocket token is invalid, expired or already used")
+
+// WSTarget is the one websocket a token may open, Pod and Container are
+// empty for watches
+type WSTarget struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Verb      string `json:"verb"`
+}
+
+type wsToken struct {
+	WSTarget
+	Identity  Identity
+	ExpiresAt time.Time
+}
+
+type wsTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]wsToken
+}
+
+var wsTokens = &wsTokenStore{tokens: make(map[string]wsToken)}
+
+// IssueWSToken mints a single use token opening target as identity
+func IssueWSToken(identity Identity, target WSTarget) (string, time.Time, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(config.WSTokenTTL())
+
+	wsTokens.mu.Lock()
+	defer wsTokens.mu.Unlock()
+	for key, t := range wsTokens.tokens {
+		if now.After(t.ExpiresAt) {
+			delete(wsTokens.tokens, key)
+		}
+	}
+	wsTokens.tokens[token] = wsToken{WSTarget: target, Identity: identity, ExpiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// wsTokenFromRequest reads the token of a websocket upgrade from the token
+// query param or a wstoken.<token> Sec-WebSocket-Protocol
+func wsTokenFromRequest(c *gin.Context) string {
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		return ""
+	}
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+	for _, protocol := range websocket.Subprotocols(c.Request) {
+		if strings.HasPrefix(protocol, wsTokenProtocolPrefix) {
+			return strings.TrimPrefix(protocol, wsTokenProtocolPrefix)
+		}
+	}
+	return ""
+}
+
+// consumeWSToken uses up token and returns its identity when the request
+// opens the websocket the token was issued for
+func consumeWSToken(c *gin.Context, token string) (Identity, error) {
+	wsTokens.mu.Lock()
+	t, ok := wsTokens.tokens[token]
+	delete(wsTokens.tokens, token)
+	wsTokens.mu.Unlock()
+	if !ok || time.Now().After(t.ExpiresAt) {
+		return Identity{}, ErrInvalidWSToken
+	}
+
+	attrs, ok := requestAttributes(c)
+	if !ok || attrs.Resource != "pods" {
+		return Identity{}, errors.New("websocket token can't be used for this route")
+	}
+	target := WSTarget{
+		Cluster:   attrs.Cluster,
+		Namespace: attrs.Namespace,
+		Pod:       c.Param("podName"),
+		Container: c.Query("container"),
+		Verb:      attrs.Verb,
+	}
+	if target != t.WSTarget {
+		return Identity{}, errors.New("websocket token was issued for another target")
+	}
+	return t.Identity, nil
+}
+
+// CheckOrigin enforces websocket.allowedOrigins, requests without an Origin
+// header don't come from browsers and are allowed
+func CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	allowed := config.WSAllowedOrigins()
+	if len(allowed) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	istioscheme "istio.io/client-go/pkg/clientset/versioned/scheme"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+const MIMEYAML = "application/yaml"
+
+var yamlMIMETypes = map[string]bool{
+	MIMEYAML:             true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+}
+
+// resources whose handler reads yaml itself, such as multi-document manifests
+var yamlNative = map[string]bool{
+	"apply": true,
+}
+
+// kinds of typed objects, which client-go leaves empty on responses
+var yamlScheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(yamlScheme)
+	_ = istioscheme.AddToScheme(yamlScheme)
+}
+
+// YAMLBody converts yaml request bodies to json, so handlers binding json
+// accept both
+func YAMLBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if !yamlMIMETypes[mediaType] || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if attrs, ok := requestAttributes(c); ok && yamlNative[attrs.Resource] {
+			c.Next()
+			return
+		}
+		appG := Gin{C: c}
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			appG.Fail(http.StatusBadRequest, err, nil)
+			c.Abort()
+			return
+		}
+		body, err = yaml.YAMLToJSON(body)
+		if err != nil {
+			appG.Fail(http.StatusBadRequest, err, nil)
+			c.Abort()
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Set("Content-Type", gin.MIMEJSON)
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		c.Next()
+	}
+}
+
+// wantsYAML reports whether a GET request prefers yaml over json
+func wantsYAML(c *gin.Context) bool {
+	if c.Request.Method != http.MethodGet {
+		return false
+	}
+	return yamlMIMETypes[c.NegotiateFormat(gin.MIMEJSON, MIMEYAML, "application/x-yaml", "text/yaml")]
+}
+
+// writeYAML writes data as a bare yaml document, typed objects get their
+// apiVersion and kind back and slices become a v1 List
+func (g *Gin) writeYAML(httpCode int, data interface{}) {
+	out, err := yaml.Marshal(yamlObject(data))
+	if err != nil {
+		g.Fail(http.StatusInternalServerError, err, nil)
+		return
+	}
+	g.C.Data(httpCode, MIMEYAML+"; charset=utf-8", out)
+}
+
+func yamlObject(data interface{}) interface{} {
+	if obj, ok := data.(runtime.Object); ok {
+		setKind(obj)
+		if meta.IsListType(obj) {
+			items, _ := meta.ExtractList(obj)
+			for _, item := range items {
+				setKind(item)
+			}
+		}
+		return obj
+	}
+	value := reflect.ValueOf(data)
+	if value.Kind() != reflect.Slice {
+		return data
+	}
+	items := make([]interface{}, value.Len())
+	for i := range items {
+		item := value.Index(i)
+		if item.Kind() != reflect.Ptr && item.CanAddr() {
+			item = item.Addr()
+		}
+		if obj, ok := item.Interface().(runtime.Object); ok {
+			setKind(obj)
+		}
+		items[i] = item.Interface()
+	}
+	return map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items}
+}
+
+func setKind(obj runtime.Object) {
+	if !obj.GetObjectKind().GroupVersionKind().Empty() {
+		return
+	}
+	if kinds, _, err := yamlScheme.ObjectKinds(obj); err == nil && len(kinds) > 0 {
+		obj.GetObjectKind().SetGroupVersionKind(kinds[0])
+	}
+}
@@ -0,0 +1,11 @@
+package config
+
+const defaultApplyFieldManager = "k8s-api-service"
+
+// ApplyFieldManager is the server-side apply field manager unless the request names one
+func ApplyFieldManager() string {
+	if manager := GetString("apply.fieldManager"); manager != "" {
+		return manager
+	}
+	return defaultApplyFieldManager
+}
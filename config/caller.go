@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	CallerModeHeader = "header"
+	CallerModeHMAC   = "hmac"
+)
+
+const defaultSignMaxSkew = 5 * time.Minute
+
+// ValidAppKey reports whether appKey can name a caller, rule subjects with a
+// colon such as user:<name> and group:<name> belong to token users
+func ValidAppKey(appKey string) bool {
+	return appKey != "" && !strings.Contains(appKey, ":")
+}
+
+// checkCallers rejects callers of the config file whose appKey is not valid
+func checkCallers() error {
+	for appKey := range viper.GetStringMap("caller") {
+		if !ValidAppKey(appKey) {
+			return fmt.Errorf("caller %q: appKey must not contain a colon", appKey)
+		}
+	}
+	return nil
+}
+
+func CallerSecret(appKey string) string {
+	return GetString("caller." + appKey + ".secret")
+}
+
+// CallerMode is how the caller authenticates, header sends the secret itself,
+// hmac signs every request with it. Defaults to header.
+func CallerMode(appKey string) string {
+	if mode := GetString("caller." + appKey + ".mode"); mode != "" {
+		return mode
+	}
+	return CallerModeHeader
+}
+
+// SignMaxSkew is the accepted clock difference of signed requests, nonces are
+// remembered for the same window
+func SignMaxSkew() time.Duration {
+	if skew := GetInt64("sign.maxSkew"); skew > 0 {
+		return time.Duration(skew) * time.Second
+	}
+	return defaultSignMaxSkew
+}
+
+const defaultRotateGracePeriod = 24 * time.Hour
+
+// RotateGracePeriod is how long a rotated caller secret stays valid
+func RotateGracePeriod() time.Duration {
+	if period := GetInt64("rotation.gracePeriod"); period > 0 {
+		return time.Duration(period) * time.Second
+	}
+	return defaultRotateGracePeriod
+}
@@ -21,6 +21,14 @@ func GetString(key string) string {
 	return viper.GetString(key)
 }
 
+func IsSet(key string) bool {
+	return viper.IsSet(key)
+}
+
+func GetBool(key string) bool {
+	return viper.GetBool(key)
+}
+
 func GetInt(key string) int {
 	return viper.GetInt(key)
 }
@@ -29,6 +37,23 @@ func GetInt64(key string) int64 {
 	return viper.GetInt64(key)
 }
 
+func GetFloat64(key string) float64 {
+	return viper.GetFloat64(key)
+}
+
+func GetStringSlice(key string) []string {
+	return viper.GetStringSlice(key)
+}
+
+func GetStringMapString(key string) map[string]string {
+	return viper.GetStringMapString(key)
+}
+
+// UnmarshalKey decodes the config value at key into rawVal
+func UnmarshalKey(key string, rawVal interface{}) error {
+	return viper.UnmarshalKey(key, rawVal)
+}
+
 func configPath() string {
 	if configPath := os.Getenv("CONFIG_PATH"); configPath == "" {
 		return "."
@@ -59,4 +84,7 @@ func Setup() {
 		log.Fatalf("config.Setup err: %v", err)
 		return
 	}
+	if err := checkCallers(); err != nil {
+		log.Fatalf("config.Setup err: %v", err)
+	}
 }
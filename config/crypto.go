@@ -0,0 +1,13 @@
+package config
+
+import "strconv"
+
+// CryptoKeyVersion is the master key version used to encrypt new kubeconfigs,
+// 0 disables encryption
+func CryptoKeyVersion() int {
+	return GetInt("crypto.keyVersion")
+}
+
+func CryptoKey(version int) string {
+	return GetStringMapString("crypto.keys")[strconv.Itoa(version)]
+}
@@ -0,0 +1,52 @@
+package config
+
+import "time"
+
+// ImpersonationCallerPrefix prefixes the appKey of callers to form the
+// impersonated kubernetes user name, defaults to caller:
+func ImpersonationCallerPrefix() string {
+	if IsSet("impersonation.callerPrefix") {
+		return GetString("impersonation.callerPrefix")
+	}
+	return "caller:"
+}
+
+// ImpersonationUserPrefix prefixes the user name of token users, defaults to oidc:
+func ImpersonationUserPrefix() string {
+	if IsSet("impersonation.userPrefix") {
+		return GetString("impersonation.userPrefix")
+	}
+	return "oidc:"
+}
+
+// ImpersonationGroupPrefix prefixes the groups of token users, defaults to oidc:
+func ImpersonationGroupPrefix() string {
+	if IsSet("impersonation.groupPrefix") {
+		return GetString("impersonation.groupPrefix")
+	}
+	return "oidc:"
+}
+
+// ImpersonationAllowSystem allows impersonating system: users and groups,
+// such as system:masters
+func ImpersonationAllowSystem() bool {
+	return GetBool("impersonation.allowSystem")
+}
+
+// ImpersonationClientTTL is how long the client of an impersonated user is
+// cached after its last request, defaults to 10 minutes
+func ImpersonationClientTTL() time.Duration {
+	if ttl := GetInt64("impersonation.clientTTL"); ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return 10 * time.Minute
+}
+
+// ImpersonationMaxClients caps the cached clients of impersonated users,
+// the least recently used are evicted first, defaults to 1000
+func ImpersonationMaxClients() int {
+	if max := GetInt("impersonation.maxClients"); max > 0 {
+		return max
+	}
+	return 1000
+}
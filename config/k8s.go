@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// default client settings for clusters that don't set their own
+
+func K8sQPS() float32 {
+	return float32(GetFloat64("k8s.qps"))
+}
+
+func K8sBurst() int {
+	return GetInt("k8s.burst")
+}
+
+func K8sTimeout() time.Duration {
+	return time.Duration(GetInt64("k8s.timeout")) * time.Second
+}
+
+func K8sProxyURL() string {
+	return GetString("k8s.proxyURL")
+}
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+const defaultMonitorInterval = 60 * time.Second
+
+// MonitorInterval is how often every registered cluster is health checked
+func MonitorInterval() time.Duration {
+	if interval := GetInt64("monitor.interval"); interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return defaultMonitorInterval
+}
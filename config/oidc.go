@@ -0,0 +1,50 @@
+package config
+
+import "time"
+
+const defaultJWKSRefresh = time.Hour
+
+// OIDCEnabled accepts Authorization: Bearer tokens next to appKey callers
+func OIDCEnabled() bool {
+	return GetBool("oidc.enabled")
+}
+
+func OIDCIssuer() string {
+	return GetString("oidc.issuer")
+}
+
+// OIDCAudience is checked against the aud claim when set
+func OIDCAudience() string {
+	return GetString("oidc.audience")
+}
+
+// OIDCJWKSFile takes precedence over OIDCJWKSURL
+func OIDCJWKSFile() string {
+	return GetString("oidc.jwksFile")
+}
+
+func OIDCJWKSURL() string {
+	return GetString("oidc.jwksURL")
+}
+
+// OIDCJWKSRefresh is how often the key set is reloaded
+func OIDCJWKSRefresh() time.Duration {
+	if refresh := GetInt64("oidc.jwksRefresh"); refresh > 0 {
+		return time.Duration(refresh) * time.Second
+	}
+	return defaultJWKSRefresh
+}
+
+func OIDCUsernameClaim() string {
+	if claim := GetString("oidc.usernameClaim"); claim != "" {
+		return claim
+	}
+	return "sub"
+}
+
+func OIDCGroupsClaim() string {
+	if claim := GetString("oidc.groupsClaim"); claim != "" {
+		return claim
+	}
+	return "groups"
+}
@@ -0,0 +1,29 @@
+package config
+
+// RateLimit is a token bucket of Rate requests per second, a zero Rate is unlimited
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitEnabled turns on request throttling and websocket session caps
+func RateLimitEnabled() bool {
+	return GetBool("rateLimit.enabled")
+}
+
+// RateLimitFor is the limit of a route group (k8s, istio, admin) per caller
+// or per cluster, burst defaults to the rate rounded up
+func RateLimitFor(group, scope string) RateLimit {
+	key := "rateLimit.groups." + group + "." + scope
+	limit := RateLimit{Rate: GetFloat64(key + ".rate"), Burst: GetInt(key + ".burst")}
+	if limit.Burst <= 0 && limit.Rate > 0 {
+		limit.Burst = int(limit.Rate + 0.999)
+	}
+	return limit
+}
+
+// WSMaxSessions caps concurrent websocket sessions per caller or per
+// cluster, 0 is unlimited
+func WSMaxSessions(scope string) int {
+	return GetInt("rateLimit.websocket." + scope)
+}
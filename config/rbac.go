@@ -0,0 +1,20 @@
+package config
+
+const (
+	RBACSourceConfig = "config"
+	RBACSourceDB     = "db"
+)
+
+// RBACEnabled turns on per caller authorization, every caller is allowed
+// everything when it is off
+func RBACEnabled() bool {
+	return GetBool("rbac.enabled")
+}
+
+// RBACSource is where caller rules are loaded from, config or db
+func RBACSource() string {
+	if source := GetString("rbac.source"); source != "" {
+		return source
+	}
+	return RBACSourceConfig
+}
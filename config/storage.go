@@ -0,0 +1,30 @@
+package config
+
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+	StorageFile     = "file"
+)
+
+// StorageDriver selects the storage backend, defaults to postgres
+func StorageDriver() string {
+	if driver := GetString("storage.driver"); driver != "" {
+		return driver
+	}
+	return StoragePostgres
+}
+
+func StorageSQLitePath() string {
+	if path := GetString("storage.sqlite.path"); path != "" {
+		return path
+	}
+	return "k8s-api-service.db"
+}
+
+// StorageFileDir is the directory the read-only file backend loads cluster yaml files from
+func StorageFileDir() string {
+	if dir := GetString("storage.file.dir"); dir != "" {
+		return dir
+	}
+	return "clusters"
+}
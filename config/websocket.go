@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+const defaultWSTokenTTL = 30 * time.Second
+
+// WSTokenTTL is how long a websocket token can wait for its connection
+func WSTokenTTL() time.Duration {
+	if ttl := GetInt64("websocket.tokenTTL"); ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return defaultWSTokenTTL
+}
+
+// WSAllowedOrigins are the browser origins allowed to open websockets, "*"
+// allows any origin and an empty list only the service's own origin
+func WSAllowedOrigins() []string {
+	return GetStringSlice("websocket.allowedOrigins")
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+)
+
+type ListQuery struct {
+	app.PageInfo
+	AppKey    string    `form:"appKey"`
+	Cluster   string    `form:"cluster"`
+	Namespace string    `form:"namespace"`
+	Resource  string    `form:"resource"`
+	Verb      string    `form:"verb"`
+	Event     string    `form:"event" binding:"omitempty,oneof=request session_start session_end"`
+	SessionID string    `form:"sessionID"`
+	Failed    bool      `form:"failed"` // 只看状态码>=400或出错的记录
+	Since     time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// List returns audit entries matching q, newest first
+func List(q ListQuery) (entries []*models.AuditModel, total int64, err error) {
+	if models.DB == nil {
+		return nil, 0, models.ErrNoDatabase
+	}
+	tx := models.DB.Model(&models.AuditModel{})
+	for column, value := range map[string]string{
+		"app_key":    q.AppKey,
+		"cluster":    q.Cluster,
+		"namespace":  q.Namespace,
+		"resource":   q.Resource,
+		"verb":       q.Verb,
+		"event":      q.Event,
+		"session_id": q.SessionID,
+	} {
+		if value != "" {
+			tx = tx.Where(column+" = ?", value)
+		}
+	}
+	if q.Failed {
+		tx = tx.Where("status_code >= ? OR error <> ?", 400, "")
+	}
+	if !q.Since.IsZero() {
+		tx = tx.Where("created_at >= ?", q.Since)
+	}
+	if !q.Until.IsZero() {
+		tx = tx.Where("created_at < ?", q.Until)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&entries).Error
+	return entries, total, err
+}
@@ -0,0 +1,111 @@
+package caller
+
+import (
+	"time"
+
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+)
+
+type CallerBody struct {
+	AppKey    string     `json:"appKey" binding:"required,excludes=:"` // 不能包含:，user:及group:为token用户保留
+	Desc      string     `json:"desc"`
+	Mode      string     `json:"mode" binding:"omitempty,oneof=header hmac"` // 默认header，仅创建时设置
+	Enabled   *bool      `json:"enabled"`                                    // 默认启用
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CallerSecret is returned once when a secret is issued
+type CallerSecret struct {
+	models.CallerModel
+	Secret string `json:"secret"`
+}
+
+func Create(body CallerBody) (res CallerSecret, err error) {
+	if models.DB == nil {
+		return res, models.ErrNoDatabase
+	}
+	if body.Mode == "" {
+		body.Mode = config.CallerModeHeader
+	}
+	res.Caller = models.Caller{
+		AppKey:    body.AppKey,
+		Desc:      body.Desc,
+		Mode:      body.Mode,
+		Enabled:   body.Enabled == nil || *body.Enabled,
+		ExpiresAt: body.ExpiresAt,
+	}
+	if res.Secret, err = res.NewSecret(); err != nil {
+		return res, err
+	}
+	err = models.DB.Create(&res.CallerModel).Error
+	return res, err
+}
+
+// Update replaces description and expiry, and the enabled flag when it is
+// given. The appKey and mode can't be changed.
+func Update(id int, body CallerBody) (err error) {
+	if _, err = Get(id); err != nil {
+		return err
+	}
+	updates := map[string]interface{}{
+		"desc":       body.Desc,
+		"expires_at": body.ExpiresAt,
+	}
+	if body.Enabled != nil {
+		updates["enabled"] = *body.Enabled
+	}
+	return models.DB.Model(&models.CallerModel{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Rotate issues a new secret, the current one stays valid for gracePeriod
+func Rotate(id int, gracePeriod time.Duration) (res CallerSecret, err error) {
+	if res.CallerModel, err = Get(id); err != nil {
+		return res, err
+	}
+	previousExpiresAt := time.Now().Add(gracePeriod)
+	res.PreviousSecretSalt = res.SecretSalt
+	res.PreviousSecretHash = res.SecretHash
+	res.PreviousSecretSealed = res.SecretSealed
+	res.PreviousExpiresAt = &previousExpiresAt
+	if res.Secret, err = res.NewSecret(); err != nil {
+		return res, err
+	}
+	err = models.DB.Model(&models.CallerModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"secret_salt":            res.SecretSalt,
+		"secret_hash":            res.SecretHash,
+		"secret_sealed":          res.SecretSealed,
+		"previous_secret_salt":   res.PreviousSecretSalt,
+		"previous_secret_hash":   res.PreviousSecretHash,
+		"previous_secret_sealed": res.PreviousSecretSealed,
+		"previous_expires_at":    res.PreviousExpiresAt,
+	}).Error
+	return res, err
+}
+
+func List(pageInfo app.PageInfo) (callers []*models.CallerModel, total int64, err error) {
+	if models.DB == nil {
+		return nil, 0, models.ErrNoDatabase
+	}
+	if err = models.DB.Model(&models.CallerModel{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = models.DB.Order("id").Offset((pageInfo.Page - 1) * pageInfo.PageSize).Limit(pageInfo.PageSize).Find(&callers).Error
+	return callers, total, err
+}
+
+func Get(id int) (caller models.CallerModel, err error) {
+	if models.DB == nil {
+		return caller, models.ErrNoDatabase
+	}
+	err = models.DB.First(&caller, id).Error
+	return caller, err
+}
+
+func Delete(id int) (err error) {
+	if models.DB == nil {
+		return models.ErrNoDatabase
+	}
+	return models.DB.Unscoped().Delete(&models.CallerModel{}, id).Error
+}
@@ -1,13 +1,25 @@
 package cluster
 
 import (
+	"errors"
+
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
 	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
+var ErrRedactedContext = errors.New("context contains redacted secrets, please submit the full kubeconfig")
+
 func Create(data models.ClusterModel) (err error) {
-	err = models.DB.Model(&models.ClusterModel{}).Create(&data).Error
+	if err = models.SealContext(&data.Cluster); err != nil {
+		return err
+	}
+	data.State = false
+	data.ClusterHealth = models.ClusterHealth{}
+	err = models.Clusters.Create(&data)
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return err
 	}
@@ -15,37 +27,120 @@ func Create(data models.ClusterModel) (err error) {
 }
 
 func Update(id int, data models.ClusterModel) (err error) {
-	err = models.DB.Model(&models.ClusterModel{}).Where("id = ?", id).Updates(&data).Error
+	origin, err := Get(id)
+	if err != nil {
+		return err
+	}
+	data.KeyVersion = 0
+	if len(data.Context) > 0 {
+		if k8s.IsRedactedKubeConfig(data.Context) {
+			return ErrRedactedContext
+		}
+		if err = models.SealContext(&data.Cluster); err != nil {
+			return err
+		}
+	}
+	err = models.Clusters.Update(id, data)
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return err
 	}
+	// drop cached clients so that rotated credentials take effect immediately
+	k8s.RemoveClient(origin.Name)
+	if data.Name != "" && data.Name != origin.Name {
+		k8s.RemoveClient(data.Name)
+	}
 	return nil
 }
 
-func List(pageInfo app.PageInfo) (clusters []*models.ClusterModel, err error) {
-	err = models.DB.Model(&models.ClusterModel{}).Offset((pageInfo.Page - 1) * pageInfo.PageSize).Limit(pageInfo.PageSize).Find(&clusters).Error
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return nil, err
+// UpdateOptions replaces the client options of a cluster, unlike Update it
+// also writes zero values so options can be reset to the config defaults
+func UpdateOptions(id int, options models.ClientOptions) (err error) {
+	origin, err := Get(id)
+	if err != nil {
+		return err
+	}
+	err = models.Clusters.UpdateColumns(id, map[string]interface{}{
+		"client_qps":             options.QPS,
+		"client_burst":           options.Burst,
+		"client_timeout":         options.Timeout,
+		"client_proxy_url":       options.ProxyURL,
+		"client_tls_server_name": options.TLSServerName,
+		"client_insecure":        options.Insecure,
+		"client_impersonate":     options.Impersonate,
+	})
+	if err != nil {
+		return err
 	}
-	return clusters, nil
+	k8s.RemoveClient(origin.Name)
+	return nil
+}
+
+func List(pageInfo app.PageInfo) (clusters []*models.ClusterModel, err error) {
+	return models.Clusters.List((pageInfo.Page-1)*pageInfo.PageSize, pageInfo.PageSize)
 }
 
 func Get(id int) (cluster models.ClusterModel, err error) {
-	err = models.DB.Model(&models.ClusterModel{}).Where("id = ?", id).First(&cluster).Error
-	return
+	return models.Clusters.Get(id)
 }
 
 func Delete(id int) (err error) {
-	//软删除
-	//err = models.DB.Model(&models.ClusterModel{}).Delete("id = ?", id).Error
-	//硬删除
-	err = models.DB.Model(&models.ClusterModel{}).Unscoped().Delete("id = ?", id).Error
-	return
+	origin, err := Get(id)
+	if err != nil {
+		return err
+	}
+	if err = models.Clusters.Delete(id); err != nil {
+		return err
+	}
+	k8s.RemoveClient(origin.Name)
+	return nil
 }
 
 func Count() (count int64, err error) {
-	if err := models.DB.Model(&models.ClusterModel{}).Count(&count).Error; err != nil {
+	return models.Clusters.Count()
+}
+
+// Redact decrypts the cluster context and strips its secrets for api responses
+func Redact(cluster *models.ClusterModel) error {
+	kubeConfig, err := models.OpenContext(cluster.Cluster)
+	if err != nil {
+		return err
+	}
+	kubeConfig, err = k8s.RedactKubeConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+	cluster.Context = datatypes.JSON(kubeConfig)
+	return nil
+}
+
+// Reencrypt seals every cluster context with the current master key,
+// rows already on the current key version are skipped
+func Reencrypt() (count int, err error) {
+	clusters, err := models.Clusters.List(0, -1)
+	if err != nil {
 		return 0, err
 	}
+	version := config.CryptoKeyVersion()
+	for _, cluster := range clusters {
+		if cluster.KeyVersion == version {
+			continue
+		}
+		kubeConfig, err := models.OpenContext(cluster.Cluster)
+		if err != nil {
+			return count, err
+		}
+		cluster.Context = datatypes.JSON(kubeConfig)
+		if err = models.SealContext(&cluster.Cluster); err != nil {
+			return count, err
+		}
+		err = models.Clusters.UpdateColumns(int(cluster.ID), map[string]interface{}{
+			"context":     cluster.Context,
+			"key_version": cluster.KeyVersion,
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
 	return count, nil
 }
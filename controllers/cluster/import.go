@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	ImportCreated  = "created"
+	ImportConflict = "conflict"
+	ImportNotFound = "notFound"
+	ImportFailed   = "error"
+)
+
+type ImportResult struct {
+	Context string `json:"context"`
+	Name    string `json:"name"`
+	Server  string `json:"server,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Import registers one cluster per selected context of the kubeconfig, all
+// contexts are imported when selected is empty
+func Import(kubeConfig []byte, selected []string, desc string) ([]ImportResult, error) {
+	contexts, err := k8s.SplitKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]k8s.KubeContext, len(contexts))
+	for _, context := range contexts {
+		byName[context.Name] = context
+	}
+	if len(selected) == 0 {
+		for _, context := range contexts {
+			selected = append(selected, context.Name)
+		}
+	}
+
+	results := make([]ImportResult, 0, len(selected))
+	for _, name := range selected {
+		context, ok := byName[name]
+		if !ok {
+			results = append(results, ImportResult{Context: name, Name: name, Status: ImportNotFound, Error: fmt.Sprintf("context %q not found in kubeconfig", name)})
+			continue
+		}
+		results = append(results, importContext(context, desc))
+	}
+	return results, nil
+}
+
+func importContext(context k8s.KubeContext, desc string) ImportResult {
+	result := ImportResult{Context: context.Name, Name: context.Name, Server: context.Server}
+	if context.Err != nil {
+		result.Status, result.Error = ImportFailed, context.Err.Error()
+		return result
+	}
+	_, err := models.Clusters.GetByName(context.Name)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		result.Status, result.Error = ImportFailed, err.Error()
+		return result
+	}
+	if err == nil {
+		result.Status, result.Error = ImportConflict, fmt.Sprintf("cluster %q already exists", context.Name)
+		return result
+	}
+	data := models.ClusterModel{Cluster: models.Cluster{
+		Name:    context.Name,
+		Desc:    desc,
+		Context: datatypes.JSON(context.Context),
+	}}
+	if err := Create(data); err != nil {
+		result.Status, result.Error = ImportFailed, err.Error()
+		// created concurrently after the lookup above
+		if errors.Is(err, models.ErrDuplicateName) {
+			result.Status, result.Error = ImportConflict, fmt.Sprintf("cluster %q already exists", context.Name)
+		}
+		return result
+	}
+	result.Status = ImportCreated
+	return result
+}
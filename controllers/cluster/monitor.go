@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/k8s"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+)
+
+// StartMonitor periodically health checks every registered cluster in the background
+func StartMonitor() {
+	log.Println("Starting cluster health monitor")
+	go func() {
+		ticker := time.NewTicker(config.MonitorInterval())
+		defer ticker.Stop()
+		for {
+			CheckAll()
+			<-ticker.C
+		}
+	}()
+}
+
+// CheckAll probes all clusters concurrently and records the results
+func CheckAll() {
+	var wg sync.WaitGroup
+	clusters, err := models.Clusters.List(0, -1)
+	if err != nil {
+		log.Printf("cluster monitor list clusters failed, err: %v", err)
+		return
+	}
+	for _, cluster := range clusters {
+		wg.Add(1)
+		go func(cluster *models.ClusterModel) {
+			defer wg.Done()
+			health := k8s.ProbeHealth(context.Background(), cluster.Name)
+			k8s.SetHealth(cluster.Name, health)
+			if err := UpdateHealth(int(cluster.ID), health); err != nil {
+				log.Printf("cluster monitor update %s health failed, err: %v", cluster.Name, err)
+			}
+		}(cluster)
+	}
+	wg.Wait()
+}
+
+func UpdateHealth(id int, health *k8s.ClusterHealth) error {
+	checkedAt := health.CheckedAt
+	return models.Clusters.UpdateHealth(id, health.Healthy, models.ClusterHealth{
+		LastCheckedAt: &checkedAt,
+		LastError:     health.Error,
+		Version:       health.Version,
+		NodesReady:    health.NodesReady,
+		NodesTotal:    health.NodesTotal,
+	})
+}
@@ -10,8 +10,12 @@ import (
 	"istio.io/client-go/pkg/apis/networking/v1beta1"
 	versionedClient "istio.io/client-go/pkg/clientset/versioned"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
 )
 
+var VirtualServiceResource = schema.GroupResource{Group: "networking.istio.io", Resource: "virtualservices"}
+
 type VSRoute struct {
 	AppName            string                                `json:"appName,omitempty"`
 	Version            string                                `json:"version,omitempty"`
@@ -94,12 +98,17 @@ func insertRoute(s []*networkingV1beta1.HTTPRoute, index int, elem *networkingV1
 	return s
 }
 
-func NewIstioClient(cluster string) (*versionedClient.Clientset, error) {
-	k8sClient, err := k8s.GetClient(cluster)
+func NewIstioClient(cluster string, as rest.ImpersonationConfig) (*versionedClient.Clientset, error) {
+	k8sClient, err := k8s.GetClientAs(cluster, as)
 	if err != nil {
 		return nil, err
 	}
-	return versionedClient.NewForConfigOrDie(k8sClient.RestConfig), nil
+	return NewIstioClientFor(k8sClient)
+}
+
+// NewIstioClientFor shares the config of k8sClient, such as its impersonation and dry run
+func NewIstioClientFor(k8sClient *k8s.K8sClient) (*versionedClient.Clientset, error) {
+	return versionedClient.NewForConfig(k8sClient.RestConfig)
 }
 
 type VSHttpRouteInterface interface {
@@ -110,11 +119,14 @@ type VSHttpRouteInterface interface {
 	Create(ctx context.Context, vsName string, vr *VSRoute) (*v1beta1.VirtualService, error)
 	Update(ctx context.Context, vsName, routeName string, vr *VSRoute) (*v1beta1.VirtualService, error)
 	Delete(ctx context.Context, vsName, routeName string, vr *VSRoute) (*v1beta1.VirtualService, error)
+	Expect(resourceVersion string) VSHttpRouteInterface
 }
 
 type VSHttpRouteOperation struct {
 	cs *versionedClient.Clientset
 	ns string
+	// resourceVersion the virtual service must still be at, empty for any
+	resourceVersion string
 }
 
 func NewVSHttpRouteOperation(cs *versionedClient.Clientset, namespace string) VSHttpRouteInterface {
@@ -132,10 +144,41 @@ func (o VSHttpRouteOperation) UpdateVS(ctx context.Context, vs *v1beta1.VirtualS
 	return o.cs.NetworkingV1beta1().VirtualServices(o.ns).Update(ctx, vs, metav1.UpdateOptions{})
 }
 
+// Expect rejects Create, Update and Delete with a conflict once the virtual
+// service has moved past resourceVersion
+func (o VSHttpRouteOperation) Expect(resourceVersion string) VSHttpRouteInterface {
+	o.resourceVersion = resourceVersion
+	return o
+}
+
+// modifyVS gets the virtual service, lets modify change it and updates it,
+// a concurrent write reruns all three with backoff
+func (o VSHttpRouteOperation) modifyVS(ctx context.Context, name string, modify func(vs *v1beta1.VirtualService) error) (*v1beta1.VirtualService, error) {
+	var result *v1beta1.VirtualService
+	err := k8s.RetryOnConflict(o.resourceVersion, func() error {
+		vs, err := o.GetVS(ctx, name)
+		if err != nil {
+			return fmt.Errorf("VSHttpRouteOperation of GetVS failed, err: %w", err)
+		}
+		if err := k8s.CheckResourceVersion(vs, o.resourceVersion, VirtualServiceResource); err != nil {
+			return err
+		}
+		if err := modify(vs); err != nil {
+			return err
+		}
+		result, err = o.UpdateVS(ctx, vs)
+		if err != nil {
+			return fmt.Errorf("VSHttpRouteOperation of Update virtualService failed, err: %w", err)
+		}
+		return nil
+	})
+	return result, err
+}
+
 func (o VSHttpRouteOperation) List(ctx context.Context, vsName, appName string) (*v1beta1.VirtualService, error) {
 	vs, err := o.GetVS(ctx, vsName)
 	if err != nil {
-		return nil, fmt.Errorf("VSHttpRouteOperation of GetVS failed, err: %s", err)
+		return nil, fmt.Errorf("VSHttpRouteOperation of GetVS failed, err: %w", err)
 	}
 
 	var routes []*networkingV1beta1.HTTPRoute
@@ -154,7 +197,7 @@ func (o VSHttpRouteOperation) List(ctx context.Context, vsName, appName string)
 func (o VSHttpRouteOperation) Get(ctx context.Context, vsName, routeName string) (*v1beta1.VirtualService, error) {
 	vs, err := o.GetVS(ctx, vsName)
 	if err != nil {
-		return nil, fmt.Errorf("VSHttpRouteOperation of GetVS failed, err: %s", err)
+		return nil, fmt.Errorf("VSHttpRouteOperation of GetVS failed, err: %w", err)
 	}
 	var routes []*networkingV1beta1.HTTPRoute
 	httpRoutes := vs.Spec.Http
@@ -168,172 +211,150 @@ func (o VSHttpRouteOperation) Get(ctx context.Context, vsName, routeName string)
 }
 
 func (o VSHttpRouteOperation) Create(ctx context.Context, vsName string, vr *VSRoute) (*v1beta1.VirtualService, error) {
-	var (
-		canaryExists     bool
-		defaultHeader    string
-		firstCanaryIndex int
-	)
-	vs, err := o.GetVS(ctx, vsName)
-	if err != nil {
-		return nil, fmt.Errorf("VSHttpRouteOperation of GetVS failed, err: %s", err)
-	}
-
-	routeName := fmt.Sprintf("%s-%s", vr.AppName, replaceVersion(vr.Version))
-	_, err = getRouteIndex(vs, routeName)
-	if err == nil {
-		return nil, fmt.Errorf("VirtualService HTTPRoute %q already exists", routeName)
-	}
+	return o.modifyVS(ctx, vsName, func(vs *v1beta1.VirtualService) error {
+		var (
+			canaryExists     bool
+			defaultHeader    string
+			firstCanaryIndex int
+		)
+		routeName := fmt.Sprintf("%s-%s", vr.AppName, replaceVersion(vr.Version))
+		if _, err := getRouteIndex(vs, routeName); err == nil {
+			return fmt.Errorf("VirtualService HTTPRoute %q already exists", routeName)
+		}
 
-	stableRouteIndex, err := getRouteIndex(vs, fmt.Sprintf("%s-stable", vr.AppName))
-	if err != nil {
-		return nil, fmt.Errorf("getRouteIndex failed, err: %s", err)
-	}
+		stableRouteIndex, err := getRouteIndex(vs, fmt.Sprintf("%s-stable", vr.AppName))
+		if err != nil {
+			return fmt.Errorf("getRouteIndex failed, err: %w", err)
+		}
 
-	switch vr.Category {
-	case "backend":
-		defaultHeader = "x-weike-forward"
-	case "frontend":
-		defaultHeader = "x-weike-fe-forward"
-	default:
-		defaultHeader = "x-weike-forward"
-	}
+		switch vr.Category {
+		case "backend":
+			defaultHeader = "x-weike-forward"
+		case "frontend":
+			defaultHeader = "x-weike-fe-forward"
+		default:
+			defaultHeader = "x-weike-forward"
+		}
 
-	defaultHttpMatch := &networkingV1beta1.HTTPMatchRequest{
-		Headers: map[string]*networkingV1beta1.StringMatch{
-			defaultHeader: {
-				MatchType: &networkingV1beta1.StringMatch_Exact{
-					Exact: vr.Version,
+		defaultHttpMatch := &networkingV1beta1.HTTPMatchRequest{
+			Headers: map[string]*networkingV1beta1.StringMatch{
+				defaultHeader: {
+					MatchType: &networkingV1beta1.StringMatch_Exact{
+						Exact: vr.Version,
+					},
 				},
 			},
-		},
-	}
-	defaultHttpRouteDestination := []*networkingV1beta1.HTTPRouteDestination{
-		{
-			Destination: &networkingV1beta1.Destination{
-				Host:   fmt.Sprintf("%s-canary.%s.svc.cluster.local", vr.AppName, vr.DeployNamespace),
-				Subset: replaceVersion(vr.Version),
+		}
+		defaultHttpRouteDestination := []*networkingV1beta1.HTTPRouteDestination{
+			{
+				Destination: &networkingV1beta1.Destination{
+					Host:   fmt.Sprintf("%s-canary.%s.svc.cluster.local", vr.AppName, vr.DeployNamespace),
+					Subset: replaceVersion(vr.Version),
+				},
+				Weight: 100,
 			},
-			Weight: 100,
-		},
-		{
-			Destination: &networkingV1beta1.Destination{
-				Host:   fmt.Sprintf("%s.%s.svc.cluster.local", vr.AppName, vr.DeployNamespace),
-				Subset: "stable",
+			{
+				Destination: &networkingV1beta1.Destination{
+					Host:   fmt.Sprintf("%s.%s.svc.cluster.local", vr.AppName, vr.DeployNamespace),
+					Subset: "stable",
+				},
+				Weight: 0,
 			},
-			Weight: 0,
-		},
-	}
-	httpRoutes := vs.Spec.Http
-	stableRoute := httpRoutes[stableRouteIndex]
-	stableUri := getVsMatchUri(stableRoute)
-	if stableUri != nil {
-		defaultHttpMatch.Uri = stableUri
-	}
-	canaryHttpRoute := &networkingV1beta1.HTTPRoute{
-		Name: routeName,
-		Match: []*networkingV1beta1.HTTPMatchRequest{
-			defaultHttpMatch,
-		},
-		Route: defaultHttpRouteDestination,
-	}
+		}
+		httpRoutes := vs.Spec.Http
+		stableRoute := httpRoutes[stableRouteIndex]
+		stableUri := getVsMatchUri(stableRoute)
+		if stableUri != nil {
+			defaultHttpMatch.Uri = stableUri
+		}
+		canaryHttpRoute := &networkingV1beta1.HTTPRoute{
+			Name: routeName,
+			Match: []*networkingV1beta1.HTTPMatchRequest{
+				defaultHttpMatch,
+			},
+			Route: defaultHttpRouteDestination,
+		}
 
-	for i, v := range httpRoutes {
-		if strings.HasPrefix(v.Name, fmt.Sprintf("%s-canary-v", vr.AppName)) {
-			canaryExists = true
-			firstCanaryIndex = i
-			break
+		for i, v := range httpRoutes {
+			if strings.HasPrefix(v.Name, fmt.Sprintf("%s-canary-v", vr.AppName)) {
+				canaryExists = true
+				firstCanaryIndex = i
+				break
+			}
 		}
-	}
-	if canaryExists {
-		httpRoutes = insertRoute(httpRoutes, firstCanaryIndex, canaryHttpRoute)
-	} else {
-		httpRoutes = insertRoute(httpRoutes, stableRouteIndex, canaryHttpRoute)
-	}
-	vs.Spec.Http = httpRoutes
-	result, err := o.UpdateVS(ctx, vs)
-	if err != nil {
-		return nil, fmt.Errorf("VSHttpRouteOperation of Update virtualService failed, err: %s", err)
-	}
-	return result, nil
+		if canaryExists {
+			httpRoutes = insertRoute(httpRoutes, firstCanaryIndex, canaryHttpRoute)
+		} else {
+			httpRoutes = insertRoute(httpRoutes, stableRouteIndex, canaryHttpRoute)
+		}
+		vs.Spec.Http = httpRoutes
+		return nil
+	})
 }
 
 func (o VSHttpRouteOperation) Update(ctx context.Context, vsName, routeName string, vr *VSRoute) (*v1beta1.VirtualService, error) {
-	vs, err := o.GetVS(ctx, vsName)
-	if err != nil {
-		return nil, fmt.Errorf("VSHttpRouteOperation of GetVS failed, err: %s", err)
-	}
-
-	routeIndex, err := getRouteIndex(vs, routeName)
-	if err != nil {
-		return nil, fmt.Errorf("getRouteIndex failed, err: %s", err)
-	}
-	stableRouteIndex, err := getRouteIndex(vs, fmt.Sprintf("%s-stable", vr.AppName))
-	if err != nil {
-		return nil, fmt.Errorf("getRouteIndex failed, err: %s", err)
-	}
-
-	httpRoutes := vs.Spec.Http
-	route := httpRoutes[routeIndex]
-	stableRoute := httpRoutes[stableRouteIndex]
-	dstIndex, dstWeight := getVsRouteDstIndexAndWeight(route.Route, vr.Version)
-	// if canary weight changed, then update weight and use stable match replace canary match
-	switch vr.CanaryWeightSwitch {
-	case true:
-		if len(route.Route) == 2 {
-			route.Route[dstIndex].Weight = vr.CanaryWeight
-			route.Route[1-dstIndex].Weight = 100 - vr.CanaryWeight
-			route.Match = stableRoute.Match
+	return o.modifyVS(ctx, vsName, func(vs *v1beta1.VirtualService) error {
+		routeIndex, err := getRouteIndex(vs, routeName)
+		if err != nil {
+			return fmt.Errorf("getRouteIndex failed, err: %w", err)
+		}
+		stableRouteIndex, err := getRouteIndex(vs, fmt.Sprintf("%s-stable", vr.AppName))
+		if err != nil {
+			return fmt.Errorf("getRouteIndex failed, err: %w", err)
 		}
-	default:
-		if stableUri := getVsMatchUri(stableRoute); stableUri != nil {
-			for _, match := range vr.HttpMatch {
-				if match.Uri == nil {
-					match.Uri = stableUri
+
+		httpRoutes := vs.Spec.Http
+		route := httpRoutes[routeIndex]
+		stableRoute := httpRoutes[stableRouteIndex]
+		dstIndex, dstWeight := getVsRouteDstIndexAndWeight(route.Route, vr.Version)
+		// if canary weight changed, then update weight and use stable match replace canary match
+		switch vr.CanaryWeightSwitch {
+		case true:
+			if len(route.Route) == 2 {
+				route.Route[dstIndex].Weight = vr.CanaryWeight
+				route.Route[1-dstIndex].Weight = 100 - vr.CanaryWeight
+				route.Match = stableRoute.Match
+			}
+		default:
+			if stableUri := getVsMatchUri(stableRoute); stableUri != nil {
+				for _, match := range vr.HttpMatch {
+					if match.Uri == nil {
+						match.Uri = stableUri
+					}
 				}
 			}
-		}
-		route.Match = vr.HttpMatch
+			route.Match = vr.HttpMatch
 
-		// reset canary weight back to default 100, stable weight back to default 0
-		if dstWeight != 100 && len(route.Route) == 2 {
-			route.Route[dstIndex].Weight = 100
-			route.Route[1-dstIndex].Weight = 0
+			// reset canary weight back to default 100, stable weight back to default 0
+			if dstWeight != 100 && len(route.Route) == 2 {
+				route.Route[dstIndex].Weight = 100
+				route.Route[1-dstIndex].Weight = 0
+			}
 		}
-	}
 
-	httpRoutes[routeIndex] = route
-	vs.Spec.Http = httpRoutes
-	result, err := o.UpdateVS(ctx, vs)
-	if err != nil {
-		return nil, fmt.Errorf("VSHttpRouteOperation of Update virtualService failed, err: %s", err)
-	}
-	return result, nil
+		httpRoutes[routeIndex] = route
+		vs.Spec.Http = httpRoutes
+		return nil
+	})
 }
 
 func (o VSHttpRouteOperation) Delete(ctx context.Context, vsName, routeName string, vr *VSRoute) (*v1beta1.VirtualService, error) {
-	vs, err := o.GetVS(ctx, vsName)
-	if err != nil {
-		return nil, fmt.Errorf("VSHttpRouteOperation of GetVS failed, err: %s", err)
-	}
-
-	if strings.HasSuffix(routeName, "stable") {
-		return nil, fmt.Errorf("stable vs rule %q cannot be deleted", routeName)
-	}
+	return o.modifyVS(ctx, vsName, func(vs *v1beta1.VirtualService) error {
+		if strings.HasSuffix(routeName, "stable") {
+			return fmt.Errorf("stable vs rule %q cannot be deleted", routeName)
+		}
 
-	routeIndex, err := getRouteIndex(vs, routeName)
-	if err != nil {
-		return nil, fmt.Errorf("getRouteIndex failed, err: %s", err)
-	}
+		routeIndex, err := getRouteIndex(vs, routeName)
+		if err != nil {
+			return fmt.Errorf("getRouteIndex failed, err: %w", err)
+		}
 
-	httpRoutes := vs.Spec.Http
-	httpRoutes = append(httpRoutes[:routeIndex], httpRoutes[routeIndex+1:]...)
-	if err = checkVsCanarySubsetExists(vs, o.ns, vr.AppName, vr.Version); err != nil {
-		return nil, fmt.Errorf("checkVsCanarySubsetExists failed, err: %s", err)
-	}
-	vs.Spec.Http = httpRoutes
-	result, err := o.UpdateVS(ctx, vs)
-	if err != nil {
-		return nil, fmt.Errorf("VSHttpRouteOperation of Update virtualService failed, err: %s", err)
-	}
-	return result, nil
+		httpRoutes := vs.Spec.Http
+		httpRoutes = append(httpRoutes[:routeIndex], httpRoutes[routeIndex+1:]...)
+		if err = checkVsCanarySubsetExists(vs, o.ns, vr.AppName, vr.Version); err != nil {
+			return fmt.Errorf("checkVsCanarySubsetExists failed, err: %w", err)
+		}
+		vs.Spec.Http = httpRoutes
+		return nil
+	})
 }
@@ -0,0 +1,156 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	ApplyCreated    = "created"
+	ApplyConfigured = "configured"
+	ApplyUnchanged  = "unchanged"
+	ApplyError      = "error"
+)
+
+type ApplyOptions struct {
+	FieldManager string
+	Force        bool
+	// Namespace is used by namespaced objects without metadata.namespace
+	Namespace string
+	// DryRun reports changes by diff, the client must be a DryRunClient
+	DryRun bool
+}
+
+type ApplyResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Result     string `json:"result"` // created, configured, unchanged or error
+	Error      string `json:"error,omitempty"`
+	// Diff is set by dry runs
+	Diff []DiffEntry `json:"diff,omitempty"`
+}
+
+// DecodeManifests splits a yaml or json stream into objects, empty documents
+// are skipped
+func DecodeManifests(r io.Reader) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	var objs []*unstructured.Unstructured
+	for i := 1; ; i++ {
+		var data map[string]interface{}
+		if err := decoder.Decode(&data); err == io.EOF {
+			return objs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i, err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: data}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("document %d: apiVersion, kind and metadata.name are required", i)
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// Applier server-side applies manifests to one cluster
+type Applier struct {
+	clusterName string
+	client      *K8sClient
+	opts        ApplyOptions
+}
+
+func NewApplier(clusterName string, client *K8sClient, opts ApplyOptions) *Applier {
+	return &Applier{clusterName: clusterName, client: client, opts: opts}
+}
+
+// Prepare resolves obj through discovery and sets its namespace, which is
+// cleared for cluster-scoped kinds
+func (a *Applier) Prepare(obj *unstructured.Unstructured) (*meta.RESTMapping, error) {
+	mapping, err := KindMapping(a.clusterName, obj.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		obj.SetNamespace("")
+	} else if obj.GetNamespace() == "" {
+		namespace := a.opts.Namespace
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		obj.SetNamespace(namespace)
+	}
+	return mapping, nil
+}
+
+// Live returns the object currently in the cluster, nil when it does not exist
+func (a *Applier) Live(ctx context.Context, mapping *meta.RESTMapping, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	live, err := a.resource(mapping, obj).Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return live, err
+}
+
+// Apply sends obj as an apply patch and reports what changed against live,
+// dry runs also return the diff
+func (a *Applier) Apply(ctx context.Context, mapping *meta.RESTMapping, obj, live *unstructured.Unstructured) (string, []DiffEntry, error) {
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return ApplyError, nil, err
+	}
+	force := a.opts.Force
+	applied, err := a.resource(mapping, obj).Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: a.opts.FieldManager,
+		Force:        &force,
+	})
+	if err != nil {
+		return ApplyError, nil, err
+	}
+	if a.opts.DryRun {
+		// nothing is written, so the resourceVersion does not tell
+		var diff []DiffEntry
+		if live == nil {
+			diff, err = Diff(nil, applied.Object)
+		} else {
+			diff, err = Diff(live.Object, applied.Object)
+		}
+		switch {
+		case err != nil:
+			return ApplyError, nil, err
+		case live == nil:
+			return ApplyCreated, diff, nil
+		case len(diff) == 0:
+			return ApplyUnchanged, diff, nil
+		default:
+			return ApplyConfigured, diff, nil
+		}
+	}
+	switch {
+	case live == nil:
+		return ApplyCreated, nil, nil
+	case live.GetResourceVersion() == applied.GetResourceVersion():
+		return ApplyUnchanged, nil, nil
+	default:
+		return ApplyConfigured, nil, nil
+	}
+}
+
+func (a *Applier) resource(mapping *meta.RESTMapping, obj *unstructured.Unstructured) dynamic.ResourceInterface {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return a.client.Dynamic.Resource(mapping.Resource)
+	}
+	return a.client.Dynamic.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+}
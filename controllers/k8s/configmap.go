@@ -7,15 +7,17 @@ import (
 	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
 type ConfigmapInterface interface {
 	Create(ctx context.Context, confMap *v1.ConfigMap) (*v1.ConfigMap, error)
-	List(ctx context.Context, queryParam metadata.CommonQueryParameter) ([]v1.ConfigMap, error)
+	List(ctx context.Context, queryParam metadata.CommonQueryParameter) (*v1.ConfigMapList, error)
 	Delete(ctx context.Context, namespace, name string) error
 	Get(ctx context.Context, namespace, name string) (*v1.ConfigMap, error)
 	Update(ctx context.Context, namespace, name string, configMap *v1.ConfigMap) (*v1.ConfigMap, error)
+	Patch(ctx context.Context, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*v1.ConfigMap, error)
 }
 
 type ConfigmapOperation struct {
@@ -32,23 +34,19 @@ func (c ConfigmapOperation) Create(ctx context.Context, confMap *v1.ConfigMap) (
 	return c.clientSet.CoreV1().ConfigMaps(confMap.Namespace).Create(ctx, confMap, metav1.CreateOptions{})
 }
 
-func (c ConfigmapOperation) List(ctx context.Context, queryParam metadata.CommonQueryParameter) ([]v1.ConfigMap, error) {
-	configMaps := c.clientSet.CoreV1().ConfigMaps(queryParam.NameSpace)
-	option := metav1.ListOptions{}
-	if queryParam.LabelSelector != "" {
-		option.LabelSelector = queryParam.LabelSelector
-	}
-	result, err := configMaps.List(ctx, option)
+func (c ConfigmapOperation) List(ctx context.Context, queryParam metadata.CommonQueryParameter) (*v1.ConfigMapList, error) {
+	configMaps := c.clientSet.CoreV1().ConfigMaps(queryParam.ListNamespace())
+	result, err := configMaps.List(ctx, queryParam.ListOptions())
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("List() configmap failed, err: %s", err))
+		return nil, fmt.Errorf("List() configmap failed, err: %w", err)
 	}
-	return result.Items, nil
+	return result, nil
 }
 
 func (c ConfigmapOperation) Delete(ctx context.Context, namespace, name string) error {
 	configMap, err := c.Get(ctx, namespace, name)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Get() configmap failed, err: %s", err))
+		return fmt.Errorf("Get() configmap failed, err: %w", err)
 	}
 	if configMap == nil {
 		return errors.New(fmt.Sprintf("configmap with namespace: %s,name: %s not found", namespace, name))
@@ -63,7 +61,7 @@ func (c ConfigmapOperation) Get(ctx context.Context, namespace, name string) (*v
 func (c ConfigmapOperation) Update(ctx context.Context, namespace, name string, configMap *v1.ConfigMap) (*v1.ConfigMap, error) {
 	oldConfigMap, err := c.Get(ctx, namespace, name)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Get() configmap failed, err: %s", err))
+		return nil, fmt.Errorf("Get() configmap failed, err: %w", err)
 	}
 	if oldConfigMap == nil {
 		return nil, errors.New(fmt.Sprintf("configmap with namespace: %s,name: %s not found", namespace, name))
@@ -72,3 +70,7 @@ func (c ConfigmapOperation) Update(ctx context.Context, namespace, name string,
 	configMap.Name = name
 	return c.clientSet.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{})
 }
+
+func (c ConfigmapOperation) Patch(ctx context.Context, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*v1.ConfigMap, error) {
+	return c.clientSet.CoreV1().ConfigMaps(namespace).Patch(ctx, name, patchType, data, opts)
+}
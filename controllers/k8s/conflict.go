@@ -0,0 +1,30 @@
+package k8s
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+)
+
+// RetryOnConflict reruns a get, modify and update fn with backoff while the
+// update conflicts with a concurrent write. An expected resourceVersion
+// pins the object, fn then runs once so a stale edit fails with a conflict.
+func RetryOnConflict(expected string, fn func() error) error {
+	if expected != "" {
+		return fn()
+	}
+	return retry.RetryOnConflict(retry.DefaultBackoff, fn)
+}
+
+// CheckResourceVersion fails with a conflict when obj has moved past the
+// expected resourceVersion, an empty expected accepts any version
+func CheckResourceVersion(obj metav1.Object, expected string, gr schema.GroupResource) error {
+	if expected == "" || obj.GetResourceVersion() == expected {
+		return nil
+	}
+	return apierrors.NewConflict(gr, obj.GetName(), fmt.Errorf(
+		"the object has been modified, expected resourceVersion %s but it is %s", expected, obj.GetResourceVersion()))
+}
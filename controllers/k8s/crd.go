@@ -3,19 +3,34 @@ package k8s
 import (
 	"context"
 	"errors"
+	"strings"
+
 	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 )
 
+// GroupResourceName is how rules name a resource, built-in groups (no dot or
+// *.k8s.io) use the plural alone, other groups add the group
+func GroupResourceName(group, resource string) string {
+	if group == "" || !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io") {
+		return resource
+	}
+	return resource + "." + group
+}
+
+// CRDInterface operates custom resources, an empty namespace addresses
+// cluster-scoped resources
 type CRDInterface interface {
 	Create(ctx context.Context, gvk schema.GroupVersionResource, data map[string]interface{}) (*unstructured.Unstructured, error)
 	Delete(ctx context.Context, gvk schema.GroupVersionResource, namespace, name string) error
 	Get(ctx context.Context, gvk schema.GroupVersionResource, namespace, name string) (*unstructured.Unstructured, error)
 	List(ctx context.Context, gvk schema.GroupVersionResource, queryParam metadata.CommonQueryParameter) (*unstructured.UnstructuredList, error)
 	Update(ctx context.Context, gvk schema.GroupVersionResource, namespace, name string, data map[string]interface{}) (*unstructured.Unstructured, error)
+	Patch(ctx context.Context, gvk schema.GroupVersionResource, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*unstructured.Unstructured, error)
 }
 
 type CRDOperation struct {
@@ -26,25 +41,28 @@ func NewCRDOperation(dyn dynamic.Interface) CRDInterface {
 	return &CRDOperation{dyn: dyn}
 }
 
+func (o *CRDOperation) resource(gvk schema.GroupVersionResource, namespace string) dynamic.ResourceInterface {
+	if namespace == "" {
+		return o.dyn.Resource(gvk)
+	}
+	return o.dyn.Resource(gvk).Namespace(namespace)
+}
+
 func (o *CRDOperation) Get(ctx context.Context, gvk schema.GroupVersionResource, namespace, name string) (*unstructured.Unstructured, error) {
-	return o.dyn.Resource(gvk).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	return o.resource(gvk, namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
+// Create uses metadata.namespace of data, cluster-scoped when it is not set
 func (o *CRDOperation) Create(ctx context.Context, gvk schema.GroupVersionResource, data map[string]interface{}) (*unstructured.Unstructured, error) {
-	metadata, ok := data["metadata"].(map[string]interface{})
-	if !ok {
+	if _, ok := data["metadata"].(map[string]interface{}); !ok {
 		return nil, errors.New("converting data metadata to map failed")
 	}
-	namespace, ok := metadata["namespace"].(string)
-	if !ok {
-		return nil, errors.New("converting data namespace to string failed")
-	}
 	obj := unstructured.Unstructured{Object: data}
-	return o.dyn.Resource(gvk).Namespace(namespace).Create(ctx, &obj, metav1.CreateOptions{})
+	return o.resource(gvk, obj.GetNamespace()).Create(ctx, &obj, metav1.CreateOptions{})
 }
 
 func (o *CRDOperation) List(ctx context.Context, gvk schema.GroupVersionResource, queryParam metadata.CommonQueryParameter) (*unstructured.UnstructuredList, error) {
-	return o.dyn.Resource(gvk).Namespace(queryParam.NameSpace).List(ctx, metav1.ListOptions{})
+	return o.resource(gvk, queryParam.ListNamespace()).List(ctx, queryParam.ListOptions())
 }
 
 func (o *CRDOperation) Update(ctx context.Context, gvk schema.GroupVersionResource, namespace, name string, data map[string]interface{}) (*unstructured.Unstructured, error) {
@@ -52,12 +70,20 @@ func (o *CRDOperation) Update(ctx context.Context, gvk schema.GroupVersionResour
 	if !ok {
 		return nil, errors.New("converting data metadata to map failed")
 	}
-	metadata["namespace"] = namespace
+	if namespace == "" {
+		delete(metadata, "namespace")
+	} else {
+		metadata["namespace"] = namespace
+	}
 	metadata["name"] = name
 	obj := unstructured.Unstructured{Object: data}
-	return o.dyn.Resource(gvk).Namespace(namespace).Update(ctx, &obj, metav1.UpdateOptions{})
+	return o.resource(gvk, namespace).Update(ctx, &obj, metav1.UpdateOptions{})
 }
 
 func (o *CRDOperation) Delete(ctx context.Context, gvk schema.GroupVersionResource, namespace, name string) error {
-	return o.dyn.Resource(gvk).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	return o.resource(gvk, namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (o *CRDOperation) Patch(ctx context.Context, gvk schema.GroupVersionResource, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*unstructured.Unstructured, error) {
+	return o.resource(gvk, namespace).Patch(ctx, name, patchType, data, opts)
 }
@@ -2,10 +2,10 @@ package k8s
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -13,6 +13,7 @@ type DeploymentInterface interface {
 	Get(ctx context.Context, namespace, name string) (*appsv1.Deployment, error)
 	Create(ctx context.Context, deployment *appsv1.Deployment) (*appsv1.Deployment, error)
 	Update(ctx context.Context, namespace, name string, deployment *appsv1.Deployment) (*appsv1.Deployment, error)
+	Patch(ctx context.Context, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*appsv1.Deployment, error)
 }
 
 type DeploymentOperation struct {
@@ -37,11 +38,15 @@ func (o DeploymentOperation) Create(ctx context.Context, deployment *appsv1.Depl
 func (o DeploymentOperation) Update(ctx context.Context, namespace, name string, deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
 	_, err := o.Get(ctx, namespace, name)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("DeploymentOperation of Get deployment failed, err: %s", err))
+		return nil, fmt.Errorf("DeploymentOperation of Get deployment failed, err: %w", err)
 	}
 	deployment, err = o.clientSet.AppsV1().Deployments(deployment.Namespace).Update(ctx, deployment, metav1.UpdateOptions{})
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("DeploymentOperation of Update deployment failed, err: %s", err))
+		return nil, fmt.Errorf("DeploymentOperation of Update deployment failed, err: %w", err)
 	}
 	return deployment, nil
 }
+
+func (o DeploymentOperation) Patch(ctx context.Context, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*appsv1.Deployment, error) {
+	return o.clientSet.AppsV1().Deployments(namespace).Patch(ctx, name, patchType, data, opts)
+}
@@ -0,0 +1,197 @@
+package k8s
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	DiffAdd     = "add"
+	DiffRemove  = "remove"
+	DiffReplace = "replace"
+)
+
+// fields the api server changes on every write, they say nothing about the
+// change itself
+var diffIgnored = map[string]bool{
+	"/metadata/managedFields":   true,
+	"/metadata/resourceVersion": true,
+}
+
+type DiffEntry struct {
+	Path string      `json:"path"` // json pointer, 如/spec/replicas
+	Op   string      `json:"op"`   // add, remove or replace
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// DryRunResult is what a dry run would have written and how it differs from
+// the live object
+type DryRunResult struct {
+	Object interface{} `json:"object"` // 删除时为空
+	Diff   []DiffEntry `json:"diff"`
+}
+
+// DryRunClient returns a copy of client whose writes all carry dryRun=All,
+// nothing done through it is persisted. The copy is built once per client and
+// reuses its connections.
+func DryRunClient(client *K8sClient) (*K8sClient, error) {
+	client.dryRunOnce.Do(func() {
+		client.dryRun, client.dryRunErr = newDryRunClient(client)
+	})
+	return client.dryRun, client.dryRunErr
+}
+
+// newDryRunClient sets a transport on the copied config instead of wrapping
+// it, client-go does not cache wrapped transports and would open new
+// connections for every client built from it, such as the istio ones
+func newDryRunClient(client *K8sClient) (*K8sClient, error) {
+	rt, err := rest.TransportFor(client.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+	restConf := rest.CopyConfig(client.RestConfig)
+	restConf.Transport = dryRunRoundTripper{rt}
+	// rt already does tls, authentication and impersonation
+	restConf.TLSClientConfig = rest.TLSClientConfig{}
+	restConf.BearerToken, restConf.BearerTokenFile = "", ""
+	restConf.Username, restConf.Password = "", ""
+	restConf.AuthProvider, restConf.ExecProvider = nil, nil
+	restConf.Impersonate = rest.ImpersonationConfig{}
+	dryRun, err := newK8sClient(restConf)
+	if err != nil {
+		return nil, err
+	}
+	dryRun.BuiltAt = client.BuiltAt
+	dryRun.Impersonate = client.Impersonate
+	return dryRun, nil
+}
+
+type dryRunRoundTripper struct {
+	rt http.RoundTripper
+}
+
+func (t dryRunRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return t.rt.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	query.Set("dryRun", metav1.DryRunAll)
+	req.URL.RawQuery = query.Encode()
+	return t.rt.RoundTrip(req)
+}
+
+// NewDryRunResult diffs result against live, a nil live is a create and a
+// nil result a delete
+func NewDryRunResult(live, result interface{}) (*DryRunResult, error) {
+	diff, err := Diff(live, result)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunResult{Object: result, Diff: diff}, nil
+}
+
+// Diff compares the json forms of two objects field by field, lists are
+// compared by index
+func Diff(old, new interface{}) ([]DiffEntry, error) {
+	oldValue, err := jsonValue(old)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := jsonValue(new)
+	if err != nil {
+		return nil, err
+	}
+	diff := make([]DiffEntry, 0)
+	diffValue("", oldValue, newValue, &diff)
+	return diff, nil
+}
+
+func jsonValue(obj interface{}) (interface{}, error) {
+	if obj == nil || reflect.ValueOf(obj).Kind() == reflect.Ptr && reflect.ValueOf(obj).IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+func diffValue(path string, old, new interface{}, diff *[]DiffEntry) {
+	if diffIgnored[path] {
+		return
+	}
+	switch {
+	case old == nil && new == nil:
+		return
+	case old == nil:
+		*diff = append(*diff, DiffEntry{Path: diffPath(path), Op: DiffAdd, New: new})
+		return
+	case new == nil:
+		*diff = append(*diff, DiffEntry{Path: diffPath(path), Op: DiffRemove, Old: old})
+		return
+	}
+	switch oldValue := old.(type) {
+	case map[string]interface{}:
+		newValue, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(oldValue)+len(newValue))
+		for key := range oldValue {
+			keys = append(keys, key)
+		}
+		for key := range newValue {
+			if _, ok := oldValue[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			diffValue(path+"/"+escapePointer(key), oldValue[key], newValue[key], diff)
+		}
+		return
+	case []interface{}:
+		newValue, ok := new.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(oldValue) || i < len(newValue); i++ {
+			var o, n interface{}
+			if i < len(oldValue) {
+				o = oldValue[i]
+			}
+			if i < len(newValue) {
+				n = newValue[i]
+			}
+			diffValue(path+"/"+strconv.Itoa(i), o, n, diff)
+		}
+		return
+	}
+	if !reflect.DeepEqual(old, new) {
+		*diff = append(*diff, DiffEntry{Path: diffPath(path), Op: DiffReplace, Old: old, New: new})
+	}
+}
+
+func diffPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
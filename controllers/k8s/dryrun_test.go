@@ -0,0 +1,122 @@
+package k8s
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiff(t *testing.T) {
+	live := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", ResourceVersion: "1"},
+		Data:       map[string]string{"a": "1"},
+	}
+	tests := []struct {
+		name string
+		old  interface{}
+		new  interface{}
+		want []DiffEntry
+	}{
+		{"equal", map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1}, []DiffEntry{}},
+		{"add, remove and replace",
+			map[string]interface{}{"keep": "x", "gone": "y", "changed": 1},
+			map[string]interface{}{"keep": "x", "changed": 2, "added": true},
+			[]DiffEntry{
+				{Path: "/added", Op: DiffAdd, New: true},
+				{Path: "/changed", Op: DiffReplace, Old: float64(1), New: float64(2)},
+				{Path: "/gone", Op: DiffRemove, Old: "y"},
+			}},
+		{"ignored paths",
+			map[string]interface{}{"metadata": map[string]interface{}{"resourceVersion": "1", "managedFields": []interface{}{"a"}}},
+			map[string]interface{}{"metadata": map[string]interface{}{"resourceVersion": "2"}},
+			[]DiffEntry{}},
+		{"lists by index",
+			map[string]interface{}{"ports": []interface{}{80, 443}},
+			map[string]interface{}{"ports": []interface{}{8080, 443, 9090}},
+			[]DiffEntry{
+				{Path: "/ports/0", Op: DiffReplace, Old: float64(80), New: float64(8080)},
+				{Path: "/ports/2", Op: DiffAdd, New: float64(9090)},
+			}},
+		{"shorter list",
+			map[string]interface{}{"ports": []interface{}{80, 443}},
+			map[string]interface{}{"ports": []interface{}{80}},
+			[]DiffEntry{{Path: "/ports/1", Op: DiffRemove, Old: float64(443)}}},
+		{"type change",
+			map[string]interface{}{"v": map[string]interface{}{"a": 1}},
+			map[string]interface{}{"v": "a"},
+			[]DiffEntry{{Path: "/v", Op: DiffReplace, Old: map[string]interface{}{"a": float64(1)}, New: "a"}}},
+		{"escaped keys",
+			map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "web"}}},
+			map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "api"}}},
+			[]DiffEntry{{Path: "/metadata/labels/app.kubernetes.io~1name", Op: DiffReplace, Old: "web", New: "api"}}},
+		{"nil live is a create", (*corev1.ConfigMap)(nil), map[string]interface{}{"a": 1},
+			[]DiffEntry{{Path: "/", Op: DiffAdd, New: map[string]interface{}{"a": float64(1)}}}},
+		{"nil result is a delete", map[string]interface{}{"a": 1}, nil,
+			[]DiffEntry{{Path: "/", Op: DiffRemove, Old: map[string]interface{}{"a": float64(1)}}}},
+		{"typed objects", live, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings", ResourceVersion: "2"},
+			Data:       map[string]string{"a": "2"},
+		}, []DiffEntry{{Path: "/data/a", Op: DiffReplace, Old: "1", New: "2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.old, tt.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDryRunRoundTripper(t *testing.T) {
+	var dryRun string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dryRun = strings.Join(r.URL.Query()["dryRun"], ",")
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: dryRunRoundTripper{http.DefaultTransport}}
+
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{http.MethodGet, ""},
+		{http.MethodHead, ""},
+		{http.MethodPost, metav1.DryRunAll},
+		{http.MethodPut, metav1.DryRunAll},
+		{http.MethodPatch, metav1.DryRunAll},
+		{http.MethodDelete, metav1.DryRunAll},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			dryRun = "unset"
+			// a dryRun the caller set must not survive next to ours
+			req, err := http.NewRequest(tt.method, server.URL+"/api/v1/namespaces/team-a/configmaps?fieldManager=test&dryRun=None", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				req.URL.RawQuery = "fieldManager=test"
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if dryRun != tt.want {
+				t.Errorf("%s sent dryRun=%q, want %q", tt.method, dryRun, tt.want)
+			}
+			if tt.want != "" && req.URL.Query().Get("dryRun") != "None" {
+				t.Errorf("the caller's request was modified: %s", req.URL)
+			}
+		})
+	}
+}
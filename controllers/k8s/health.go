@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var ErrClusterUnhealthy = errors.New("cluster is unhealthy")
+
+type ClusterHealth struct {
+	Healthy    bool      `json:"healthy"`
+	CheckedAt  time.Time `json:"checkedAt"`
+	Error      string    `json:"error,omitempty"`
+	Version    string    `json:"version,omitempty"`
+	NodesReady int       `json:"nodesReady"`
+	NodesTotal int       `json:"nodesTotal"`
+}
+
+var clusterHealth = &sync.Map{}
+
+// SetHealth records the latest probe result used by GetClient to fail fast
+func SetHealth(clusterName string, health *ClusterHealth) {
+	clusterHealth.Store(clusterName, health)
+}
+
+func RemoveHealth(clusterName string) {
+	clusterHealth.Delete(clusterName)
+}
+
+// CheckHealth returns ErrClusterUnhealthy when the last probe of the cluster failed
+func CheckHealth(clusterName string) error {
+	value, ok := clusterHealth.Load(clusterName)
+	if !ok {
+		return nil
+	}
+	health := value.(*ClusterHealth)
+	if health.Healthy {
+		return nil
+	}
+	return fmt.Errorf("%w: %s failed its health check at %s: %s",
+		ErrClusterUnhealthy, clusterName, health.CheckedAt.Format(time.RFC3339), health.Error)
+}
+
+// ProbeHealth checks /readyz and the server version of a registered cluster
+// and counts its ready nodes. It bypasses the fail fast check of GetClient so that
+// unhealthy clusters are detected once they recover.
+func ProbeHealth(ctx context.Context, clusterName string) *ClusterHealth {
+	health := &ClusterHealth{CheckedAt: time.Now()}
+	k8sClient, err := loadClient(clusterName)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	probeConf := rest.CopyConfig(k8sClient.RestConfig)
+	probeConf.Timeout = defaultProbeTimeout
+	clientSet, err := kubernetes.NewForConfig(probeConf)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultProbeTimeout)
+	defer cancel()
+	if _, err := clientSet.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err != nil {
+		health.Error = fmt.Sprintf("readyz: %s", err)
+		return health
+	}
+	version, err := clientSet.Discovery().ServerVersion()
+	if err != nil {
+		health.Error = fmt.Sprintf("version: %s", err)
+		return health
+	}
+	health.Version = version.GitVersion
+	health.Healthy = true
+
+	// node counts are best effort, kubeconfigs scoped to a namespace or
+	// impersonating callers may not list nodes
+	nodes, err := clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		health.Error = fmt.Sprintf("nodes: %s", err)
+		return health
+	}
+	health.NodesTotal = len(nodes.Items)
+	for _, node := range nodes.Items {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				health.NodesReady++
+			}
+		}
+	}
+	return health
+}
@@ -6,15 +6,17 @@ import (
 	"github.com/mizhexiaoxiao/k8s-api-service/models/metadata"
 	v1 "k8s.io/api/autoscaling/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
 type HorizontalPodAutoScalerInterface interface {
 	Create(ctx context.Context, scaler *v1.HorizontalPodAutoscaler) (*v1.HorizontalPodAutoscaler, error)
-	List(ctx context.Context, queryParam metadata.CommonQueryParameter) ([]v1.HorizontalPodAutoscaler, error)
+	List(ctx context.Context, queryParam metadata.CommonQueryParameter) (*v1.HorizontalPodAutoscalerList, error)
 	Get(ctx context.Context, namespace, name string) (*v1.HorizontalPodAutoscaler, error)
 	Update(ctx context.Context, namespace, name string, scaler *v1.HorizontalPodAutoscaler) (*v1.HorizontalPodAutoscaler, error)
 	Delete(ctx context.Context, namespace, name string) error
+	Patch(ctx context.Context, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*v1.HorizontalPodAutoscaler, error)
 }
 
 type HorizontalPodAutoScalerOperation struct {
@@ -32,14 +34,9 @@ func (o *HorizontalPodAutoScalerOperation) Create(ctx context.Context, scaler *v
 	return autoscalers.Create(ctx, scaler, metav1.CreateOptions{})
 }
 
-func (o *HorizontalPodAutoScalerOperation) List(ctx context.Context, queryParam metadata.CommonQueryParameter) ([]v1.HorizontalPodAutoscaler, error) {
-	autoscalers := o.clientSet.AutoscalingV1().HorizontalPodAutoscalers(queryParam.NameSpace)
-	option := metav1.ListOptions{LabelSelector: queryParam.LabelSelector}
-	result, err := autoscalers.List(ctx, option)
-	if err != nil {
-		return nil, err
-	}
-	return result.Items, nil
+func (o *HorizontalPodAutoScalerOperation) List(ctx context.Context, queryParam metadata.CommonQueryParameter) (*v1.HorizontalPodAutoscalerList, error) {
+	autoscalers := o.clientSet.AutoscalingV1().HorizontalPodAutoscalers(queryParam.ListNamespace())
+	return autoscalers.List(ctx, queryParam.ListOptions())
 }
 
 func (o *HorizontalPodAutoScalerOperation) Get(ctx context.Context, namespace, name string) (*v1.HorizontalPodAutoscaler, error) {
@@ -68,3 +65,7 @@ func (o *HorizontalPodAutoScalerOperation) Delete(ctx context.Context, namespace
 	}
 	return errors.New("originScaler not match delete object")
 }
+
+func (o *HorizontalPodAutoScalerOperation) Patch(ctx context.Context, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*v1.HorizontalPodAutoscaler, error) {
+	return o.clientSet.AutoscalingV1().HorizontalPodAutoscalers(namespace).Patch(ctx, name, patchType, data, opts)
+}
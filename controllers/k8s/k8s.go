@@ -2,10 +2,19 @@ package k8s
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
 	"github.com/mizhexiaoxiao/k8s-api-service/models"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -17,11 +26,45 @@ import (
 type K8sClient struct {
 	RestConfig *rest.Config
 	ClientV1   *kubernetes.Clientset
+	// StreamClientV1 has no request timeout, use it for watches and log streams
+	StreamClientV1 *kubernetes.Clientset
+	Dynamic        dynamic.Interface
+	BuiltAt        time.Time
+	// Impersonate is set when the cluster acts as the caller of each request
+	Impersonate bool
+	// lastUsed is the unix nano time of the last GetClientAs of an
+	// impersonated client, read and written atomically
+	lastUsed int64
+	// the dry run copy shares the transport and is dropped with the client
+	dryRunOnce sync.Once
+	dryRun     *K8sClient
+	dryRunErr  error
 }
 
+type ClientInfo struct {
+	Cluster     string    `json:"cluster"`
+	Host        string    `json:"host"`
+	BuiltAt     time.Time `json:"builtAt"`
+	Impersonate string    `json:"impersonate,omitempty"` // 模拟的用户
+}
+
+var ErrNoImpersonation = errors.New("cluster impersonates callers, but the request has no identity")
+
+var ErrSystemImpersonation = errors.New("impersonating system: users and groups is not allowed")
+
+// keys of impersonated clients are <cluster>\x00<user>\x00<groups>
 var k8sClients = &sync.Map{} //并发map
 
+// GetClient returns the cached client of the cluster, clusters that failed
+// their last health check are rejected right away instead of hanging
 func GetClient(clusterName string) (*K8sClient, error) {
+	if err := CheckHealth(clusterName); err != nil {
+		return nil, err
+	}
+	return loadClient(clusterName)
+}
+
+func loadClient(clusterName string) (*K8sClient, error) {
 	var (
 		cluster   models.Cluster
 		context   clientcmdapiv1.Config
@@ -33,27 +76,175 @@ func GetClient(clusterName string) (*K8sClient, error) {
 		return client.(*K8sClient), nil
 	}
 
-	err = models.DB.Model(&models.ClusterModel{}).Where("name = ?", clusterName).First(&cluster).Error
+	clusterModel, err := models.Clusters.GetByName(clusterName)
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(cluster.Context, &context)
+	cluster = clusterModel.Cluster
+	kubeConfig, err := models.OpenContext(cluster)
 	if err != nil {
 		return nil, err
 	}
-	clientset, restConf, err := BuildClient(cluster.Name, context)
+	err = json.Unmarshal(kubeConfig, &context)
 	if err != nil {
 		return nil, err
 	}
-	k8sClient = &K8sClient{
-		RestConfig: restConf,
-		ClientV1:   clientset,
+	_, restConf, err := BuildClient(context, cluster.ClientOptions)
+	if err != nil {
+		return nil, err
+	}
+	if k8sClient, err = newK8sClient(restConf); err != nil {
+		return nil, err
 	}
+	k8sClient.Impersonate = cluster.ClientOptions.Impersonate
 
 	k8sClients.Store(clusterName, k8sClient)
 	return k8sClient, nil
 }
 
+// GetClientAs returns the client of the cluster acting as the given user.
+// Clusters without impersonation ignore the user and share one client.
+func GetClientAs(clusterName string, as rest.ImpersonationConfig) (*K8sClient, error) {
+	client, err := GetClient(clusterName)
+	if err != nil || !client.Impersonate {
+		return client, err
+	}
+	if as.UserName == "" {
+		return nil, ErrNoImpersonation
+	}
+	if !config.ImpersonationAllowSystem() && impersonatesSystem(as) {
+		return nil, ErrSystemImpersonation
+	}
+	key := strings.Join([]string{clusterName, as.UserName, strings.Join(as.Groups, ",")}, "\x00")
+	if cached, ok := k8sClients.Load(key); ok {
+		impersonated := cached.(*K8sClient)
+		atomic.StoreInt64(&impersonated.lastUsed, time.Now().UnixNano())
+		return impersonated, nil
+	}
+	restConf := rest.CopyConfig(client.RestConfig)
+	restConf.Impersonate = as
+	impersonated, err := newK8sClient(restConf)
+	if err != nil {
+		return nil, err
+	}
+	impersonated.Impersonate = true
+	impersonated.lastUsed = time.Now().UnixNano()
+	k8sClients.Store(key, impersonated)
+	pruneImpersonated()
+	return impersonated, nil
+}
+
+// impersonatesSystem reports whether as names a system: user or group, which
+// the api server grants built-in rights such as cluster-admin
+func impersonatesSystem(as rest.ImpersonationConfig) bool {
+	if strings.HasPrefix(as.UserName, "system:") {
+		return true
+	}
+	for _, group := range as.Groups {
+		if strings.HasPrefix(group, "system:") {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneImpersonated evicts impersonated clients idle for longer than
+// impersonation.clientTTL, then the least recently used ones beyond
+// impersonation.maxClients
+func pruneImpersonated() {
+	type entry struct {
+		key      interface{}
+		lastUsed int64
+	}
+	idleSince := time.Now().Add(-config.ImpersonationClientTTL()).UnixNano()
+	var entries []entry
+	k8sClients.Range(func(key, value interface{}) bool {
+		if !strings.Contains(key.(string), "\x00") {
+			return true
+		}
+		lastUsed := atomic.LoadInt64(&value.(*K8sClient).lastUsed)
+		if lastUsed < idleSince {
+			k8sClients.Delete(key)
+		} else {
+			entries = append(entries, entry{key, lastUsed})
+		}
+		return true
+	})
+	if max := config.ImpersonationMaxClients(); len(entries) > max {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].lastUsed < entries[j].lastUsed })
+		for _, e := range entries[:len(entries)-max] {
+			k8sClients.Delete(e.key)
+		}
+	}
+}
+
+func newK8sClient(restConf *rest.Config) (*K8sClient, error) {
+	clientset, err := kubernetes.NewForConfig(restConf)
+	if err != nil {
+		return nil, err
+	}
+	// watches and log streams must outlive the request timeout
+	streamConf := rest.CopyConfig(restConf)
+	streamConf.Timeout = 0
+	streamClientset, err := kubernetes.NewForConfig(streamConf)
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := dynamic.NewForConfig(restConf)
+	if err != nil {
+		return nil, err
+	}
+	return &K8sClient{
+		RestConfig:     restConf,
+		ClientV1:       clientset,
+		StreamClientV1: streamClientset,
+		Dynamic:        dyn,
+		BuiltAt:        time.Now(),
+	}, nil
+}
+
+// RemoveClient evicts the cached clients of the cluster, including the
+// impersonated ones, the next GetClient rebuilds them from the database
+func RemoveClient(clusterName string) {
+	k8sClients.Range(func(key, value interface{}) bool {
+		if name := key.(string); name == clusterName || strings.HasPrefix(name, clusterName+"\x00") {
+			k8sClients.Delete(key)
+		}
+		return true
+	})
+	RemoveResourceMapper(clusterName)
+	// rotated credentials deserve a new chance before the next health check
+	RemoveHealth(clusterName)
+}
+
+// RefreshClient rebuilds the cached client from the database
+func RefreshClient(clusterName string) (*K8sClient, error) {
+	RemoveClient(clusterName)
+	return GetClient(clusterName)
+}
+
+// ListClients returns the cached clients sorted by cluster name and user
+func ListClients() []ClientInfo {
+	clients := make([]ClientInfo, 0)
+	k8sClients.Range(func(key, value interface{}) bool {
+		client := value.(*K8sClient)
+		clients = append(clients, ClientInfo{
+			Cluster:     strings.SplitN(key.(string), "\x00", 2)[0],
+			Host:        client.RestConfig.Host,
+			BuiltAt:     client.BuiltAt,
+			Impersonate: client.RestConfig.Impersonate.UserName,
+		})
+		return true
+	})
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].Cluster != clients[j].Cluster {
+			return clients[i].Cluster < clients[j].Cluster
+		}
+		return clients[i].Impersonate < clients[j].Impersonate
+	})
+	return clients
+}
+
 // func GetLocalClient(clusterID string) (*kubernetes.Clientset, error) {
 // 	client, ok := k8sClients.Load(clusterID)
 // 	if ok {
@@ -98,21 +289,23 @@ const (
 	defaultResyncPeriod = 30 * time.Second
 )
 
-func BuildClient(server string, configV1 clientcmdapiv1.Config) (*kubernetes.Clientset, *rest.Config, error) {
+// BuildClient builds a clientset for the current context of configV1
+func BuildClient(configV1 clientcmdapiv1.Config, options models.ClientOptions) (*kubernetes.Clientset, *rest.Config, error) {
 	configObject, err := clientcmdlatest.Scheme.ConvertToVersion(&configV1, clientcmdapi.SchemeGroupVersion)
+	if err != nil {
+		return nil, nil, err
+	}
 	configInternal := configObject.(*clientcmdapi.Config)
 
-	clientConfig, err := clientcmd.NewDefaultClientConfig(*configInternal,
-		&clientcmd.ConfigOverrides{
-			ClusterDefaults: clientcmdapi.Cluster{Server: server},
-		}).ClientConfig()
+	clientConfig, err := clientcmd.NewDefaultClientConfig(*configInternal, &clientcmd.ConfigOverrides{}).ClientConfig()
 
 	if err != nil {
 		return nil, nil, err
 	}
 
-	clientConfig.QPS = defaultQPS
-	clientConfig.Burst = defaultBurst
+	if err = applyClientOptions(clientConfig, options); err != nil {
+		return nil, nil, err
+	}
 
 	clientSet, err := kubernetes.NewForConfig(clientConfig)
 
@@ -122,3 +315,56 @@ func BuildClient(server string, configV1 clientcmdapiv1.Config) (*kubernetes.Cli
 
 	return clientSet, clientConfig, nil
 }
+
+// applyClientOptions applies the cluster's client options, falling back to
+// the config file defaults for options the cluster doesn't set
+func applyClientOptions(clientConfig *rest.Config, options models.ClientOptions) error {
+	clientConfig.QPS = firstFloat32(options.QPS, config.K8sQPS(), defaultQPS)
+	clientConfig.Burst = firstInt(options.Burst, config.K8sBurst(), defaultBurst)
+
+	clientConfig.Timeout = config.K8sTimeout()
+	if options.Timeout > 0 {
+		clientConfig.Timeout = time.Duration(options.Timeout) * time.Second
+	}
+
+	proxyURL := options.ProxyURL
+	if proxyURL == "" {
+		proxyURL = config.K8sProxyURL()
+	}
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy url %q: %v", proxyURL, err)
+		}
+		clientConfig.Proxy = http.ProxyURL(u)
+	}
+
+	if options.TLSServerName != "" {
+		clientConfig.TLSClientConfig.ServerName = options.TLSServerName
+	}
+	if options.Insecure {
+		// client-go refuses a root CA together with insecure
+		clientConfig.TLSClientConfig.Insecure = true
+		clientConfig.TLSClientConfig.CAFile = ""
+		clientConfig.TLSClientConfig.CAData = nil
+	}
+	return nil
+}
+
+func firstFloat32(values ...float32) float32 {
+	for _, value := range values {
+		if value > 0 {
+			return value
+		}
+	}
+	return 0
+}
+
+func firstInt(values ...int) int {
+	for _, value := range values {
+		if value > 0 {
+			return value
+		}
+	}
+	return 0
+}
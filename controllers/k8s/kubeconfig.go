@@ -0,0 +1,144 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+const redacted = "REDACTED"
+
+type KubeContext struct {
+	Name   string
+	Server string
+	// Context is the kubeconfig json reduced to this context, its cluster and user
+	Context []byte
+	Err     error
+}
+
+// SplitKubeConfig parses a kubeconfig yaml or json document and returns one
+// self-contained kubeconfig per context, sorted by context name
+func SplitKubeConfig(raw []byte) ([]KubeContext, error) {
+	kubeConfig, err := clientcmd.Load(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(kubeConfig.Contexts) == 0 {
+		return nil, fmt.Errorf("kubeconfig has no contexts")
+	}
+	names := make([]string, 0, len(kubeConfig.Contexts))
+	for name := range kubeConfig.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	contexts := make([]KubeContext, 0, len(names))
+	for _, name := range names {
+		kubeContext := KubeContext{Name: name}
+		kubeContext.Server, kubeContext.Context, kubeContext.Err = minifyKubeConfig(kubeConfig, name)
+		contexts = append(contexts, kubeContext)
+	}
+	return contexts, nil
+}
+
+func minifyKubeConfig(kubeConfig *clientcmdapi.Config, contextName string) (string, []byte, error) {
+	minified := kubeConfig.DeepCopy()
+	minified.CurrentContext = contextName
+	if err := clientcmdapi.MinifyConfig(minified); err != nil {
+		return "", nil, err
+	}
+	var server string
+	for _, cluster := range minified.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return "", nil, fmt.Errorf("cluster references certificate-authority file %q, embed it as certificate-authority-data", cluster.CertificateAuthority)
+		}
+		server = cluster.Server
+	}
+	for _, authInfo := range minified.AuthInfos {
+		if authInfo.ClientCertificate != "" || authInfo.ClientKey != "" || authInfo.TokenFile != "" {
+			return "", nil, fmt.Errorf("user references local credential files, embed them as client-certificate-data, client-key-data or token")
+		}
+	}
+	if server == "" {
+		return "", nil, fmt.Errorf("context %q has no cluster server", contextName)
+	}
+	configObject, err := clientcmdlatest.Scheme.ConvertToVersion(minified, clientcmdapiv1.SchemeGroupVersion)
+	if err != nil {
+		return "", nil, err
+	}
+	context, err := json.Marshal(configObject)
+	if err != nil {
+		return "", nil, err
+	}
+	return server, context, nil
+}
+
+// RedactKubeConfig replaces private keys, tokens and passwords in the
+// kubeconfig json so it can be returned by the api
+func RedactKubeConfig(raw []byte) ([]byte, error) {
+	var kubeConfig clientcmdapiv1.Config
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	if err := json.Unmarshal(raw, &kubeConfig); err != nil {
+		return nil, err
+	}
+	for i := range kubeConfig.AuthInfos {
+		authInfo := &kubeConfig.AuthInfos[i].AuthInfo
+		if len(authInfo.ClientKeyData) > 0 {
+			authInfo.ClientKeyData = []byte(redacted)
+		}
+		if authInfo.Token != "" {
+			authInfo.Token = redacted
+		}
+		if authInfo.Password != "" {
+			authInfo.Password = redacted
+		}
+		if authInfo.AuthProvider != nil {
+			for key := range authInfo.AuthProvider.Config {
+				authInfo.AuthProvider.Config[key] = redacted
+			}
+		}
+		if authInfo.Exec != nil {
+			for j := range authInfo.Exec.Env {
+				authInfo.Exec.Env[j].Value = redacted
+			}
+		}
+	}
+	return json.Marshal(kubeConfig)
+}
+
+// IsRedactedKubeConfig reports whether raw still carries redacted secrets,
+// e.g. when a client sends back a cluster it got from GetCluster
+func IsRedactedKubeConfig(raw []byte) bool {
+	var kubeConfig clientcmdapiv1.Config
+	if err := json.Unmarshal(raw, &kubeConfig); err != nil {
+		return false
+	}
+	for _, namedAuthInfo := range kubeConfig.AuthInfos {
+		authInfo := namedAuthInfo.AuthInfo
+		if string(authInfo.ClientKeyData) == redacted || authInfo.Token == redacted || authInfo.Password == redacted {
+			return true
+		}
+		if authInfo.AuthProvider != nil {
+			for _, value := range authInfo.AuthProvider.Config {
+				if value == redacted {
+					return true
+				}
+			}
+		}
+		if authInfo.Exec != nil {
+			for _, env := range authInfo.Exec.Env {
+				if env.Value == redacted {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
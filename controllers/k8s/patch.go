@@ -0,0 +1,37 @@
+package k8s
+
+import (
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var ErrUnsupportedPatchType = errors.New("unsupported patch content type, use application/json-patch+json, application/merge-patch+json, application/strategic-merge-patch+json or application/apply-patch+yaml")
+
+// plain json, also what yaml bodies are converted to, is a merge patch
+var patchTypes = map[string]types.PatchType{
+	string(types.JSONPatchType):           types.JSONPatchType,
+	string(types.MergePatchType):          types.MergePatchType,
+	string(types.StrategicMergePatchType): types.StrategicMergePatchType,
+	string(types.ApplyPatchType):          types.ApplyPatchType,
+	"application/json":                    types.MergePatchType,
+}
+
+// PatchType maps the media type of a patch body to its patch type
+func PatchType(contentType string) (types.PatchType, error) {
+	if patchType, ok := patchTypes[contentType]; ok {
+		return patchType, nil
+	}
+	return "", ErrUnsupportedPatchType
+}
+
+// PatchOptions sets the field manager, which apply patches require, and
+// force, which only apply patches accept
+func PatchOptions(patchType types.PatchType, fieldManager string, force bool) metav1.PatchOptions {
+	opts := metav1.PatchOptions{FieldManager: fieldManager}
+	if patchType == types.ApplyPatchType {
+		opts.Force = &force
+	}
+	return opts
+}
@@ -2,8 +2,28 @@ package k8s
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	OutputTable = "table"
+	OutputWide  = "wide"
 )
 
 type PodFormatStatus struct {
@@ -104,3 +124,561 @@ func hasPodReadyCondition(conditions []corev1.PodCondition) bool {
 	}
 	return false
 }
+
+// columns of priority 1 are only printed by output wide, like kubectl
+var (
+	defaultColumns = []metav1.TableColumnDefinition{
+		{Name: "Name", Type: "string", Format: "name"},
+		{Name: "Age", Type: "string"},
+	}
+	podColumns = []metav1.TableColumnDefinition{
+		{Name: "Name", Type: "string", Format: "name"},
+		{Name: "Ready", Type: "string"},
+		{Name: "Status", Type: "string"},
+		{Name: "Restarts", Type: "string"},
+		{Name: "Age", Type: "string"},
+		{Name: "IP", Type: "string", Priority: 1},
+		{Name: "Node", Type: "string", Priority: 1},
+		{Name: "Nominated Node", Type: "string", Priority: 1},
+		{Name: "Readiness Gates", Type: "string", Priority: 1},
+	}
+	deploymentColumns = []metav1.TableColumnDefinition{
+		{Name: "Name", Type: "string", Format: "name"},
+		{Name: "Ready", Type: "string"},
+		{Name: "Up-to-date", Type: "integer"},
+		{Name: "Available", Type: "integer"},
+		{Name: "Age", Type: "string"},
+		{Name: "Containers", Type: "string", Priority: 1},
+		{Name: "Images", Type: "string", Priority: 1},
+		{Name: "Selector", Type: "string", Priority: 1},
+	}
+	jobColumns = []metav1.TableColumnDefinition{
+		{Name: "Name", Type: "string", Format: "name"},
+		{Name: "Completions", Type: "string"},
+		{Name: "Duration", Type: "string"},
+		{Name: "Age", Type: "string"},
+		{Name: "Containers", Type: "string", Priority: 1},
+		{Name: "Images", Type: "string", Priority: 1},
+		{Name: "Selector", Type: "string", Priority: 1},
+	}
+	cronJobColumns = []metav1.TableColumnDefinition{
+		{Name: "Name", Type: "string", Format: "name"},
+		{Name: "Schedule", Type: "string"},
+		{Name: "Suspend", Type: "boolean"},
+		{Name: "Active", Type: "integer"},
+		{Name: "Last Schedule", Type: "string"},
+		{Name: "Age", Type: "string"},
+		{Name: "Containers", Type: "string", Priority: 1},
+		{Name: "Images", Type: "string", Priority: 1},
+		{Name: "Selector", Type: "string", Priority: 1},
+	}
+	nodeColumns = []metav1.TableColumnDefinition{
+		{Name: "Name", Type: "string", Format: "name"},
+		{Name: "Status", Type: "string"},
+		{Name: "Roles", Type: "string"},
+		{Name: "Age", Type: "string"},
+		{Name: "Version", Type: "string"},
+		{Name: "Internal-IP", Type: "string", Priority: 1},
+		{Name: "External-IP", Type: "string", Priority: 1},
+		{Name: "OS-Image", Type: "string", Priority: 1},
+		{Name: "Kernel-Version", Type: "string", Priority: 1},
+		{Name: "Container-Runtime", Type: "string", Priority: 1},
+	}
+	serviceColumns = []metav1.TableColumnDefinition{
+		{Name: "Name", Type: "string", Format: "name"},
+		{Name: "Type", Type: "string"},
+		{Name: "Cluster-IP", Type: "string"},
+		{Name: "External-IP", Type: "string"},
+		{Name: "Port(s)", Type: "string"},
+		{Name: "Age", Type: "string"},
+		{Name: "Selector", Type: "string", Priority: 1},
+	}
+	hpaColumns = []metav1.TableColumnDefinition{
+		{Name: "Name", Type: "string", Format: "name"},
+		{Name: "Reference", Type: "string"},
+		{Name: "Targets", Type: "string"},
+		{Name: "MinPods", Type: "string"},
+		{Name: "MaxPods", Type: "integer"},
+		{Name: "Replicas", Type: "integer"},
+		{Name: "Age", Type: "string"},
+	}
+)
+
+// PrintTable renders a list as the rows kubectl get prints, wide adds the
+// columns of -o wide. Kinds without a printer get name and age. Each row
+// carries the metadata of its object, ages are computed from the
+// timestamps as the api server returned them.
+func PrintTable(list runtime.Object, wide bool) (*metav1.Table, error) {
+	list = typedList(list)
+	var (
+		columns []metav1.TableColumnDefinition
+		objects []metav1.Object
+		cells   [][]interface{}
+	)
+	switch l := list.(type) {
+	case *corev1.PodList:
+		columns = podColumns
+		for i := range l.Items {
+			objects = append(objects, &l.Items[i])
+			cells = append(cells, printPod(&l.Items[i]))
+		}
+	case *appsv1.DeploymentList:
+		columns = deploymentColumns
+		for i := range l.Items {
+			objects = append(objects, &l.Items[i])
+			cells = append(cells, printDeployment(&l.Items[i]))
+		}
+	case *batchv1.JobList:
+		columns = jobColumns
+		for i := range l.Items {
+			objects = append(objects, &l.Items[i])
+			cells = append(cells, printJob(&l.Items[i]))
+		}
+	case *batchv1beta1.CronJobList:
+		columns = cronJobColumns
+		for i := range l.Items {
+			cronJob := &l.Items[i]
+			objects = append(objects, cronJob)
+			cells = append(cells, printCronJob(cronJob, cronJob.Spec.Schedule, cronJob.Spec.Suspend,
+				len(cronJob.Status.Active), cronJob.Status.LastScheduleTime, &cronJob.Spec.JobTemplate.Spec))
+		}
+	case *batchv1.CronJobList:
+		columns = cronJobColumns
+		for i := range l.Items {
+			cronJob := &l.Items[i]
+			objects = append(objects, cronJob)
+			cells = append(cells, printCronJob(cronJob, cronJob.Spec.Schedule, cronJob.Spec.Suspend,
+				len(cronJob.Status.Active), cronJob.Status.LastScheduleTime, &cronJob.Spec.JobTemplate.Spec))
+		}
+	case *corev1.NodeList:
+		columns = nodeColumns
+		for i := range l.Items {
+			objects = append(objects, &l.Items[i])
+			cells = append(cells, printNode(&l.Items[i]))
+		}
+	case *corev1.ServiceList:
+		columns = serviceColumns
+		for i := range l.Items {
+			objects = append(objects, &l.Items[i])
+			cells = append(cells, printService(&l.Items[i]))
+		}
+	case *autoscalingv1.HorizontalPodAutoscalerList:
+		columns = hpaColumns
+		for i := range l.Items {
+			objects = append(objects, &l.Items[i])
+			cells = append(cells, printHorizontalPodAutoscaler(&l.Items[i]))
+		}
+	case *autoscalingv2.HorizontalPodAutoscalerList:
+		// the version the resources routes read on current clusters
+		columns = hpaColumns
+		for i := range l.Items {
+			objects = append(objects, &l.Items[i])
+			cells = append(cells, printHorizontalPodAutoscalerV2(&l.Items[i]))
+		}
+	default:
+		columns = defaultColumns
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			obj, err := meta.Accessor(item)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, obj)
+			cells = append(cells, []interface{}{obj.GetName(), age(obj.GetCreationTimestamp())})
+		}
+	}
+
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		return nil, err
+	}
+	table := &metav1.Table{
+		TypeMeta: metav1.TypeMeta{APIVersion: metav1.SchemeGroupVersion.String(), Kind: "Table"},
+		ListMeta: metav1.ListMeta{
+			ResourceVersion:    listMeta.GetResourceVersion(),
+			Continue:           listMeta.GetContinue(),
+			RemainingItemCount: listMeta.GetRemainingItemCount(),
+		},
+		Rows: make([]metav1.TableRow, len(objects)),
+	}
+	shown := make([]int, 0, len(columns))
+	for i, column := range columns {
+		if wide || column.Priority == 0 {
+			shown = append(shown, i)
+			table.ColumnDefinitions = append(table.ColumnDefinitions, column)
+		}
+	}
+	for i, obj := range objects {
+		row := make([]interface{}, len(shown))
+		for j, column := range shown {
+			row[j] = cells[i][column]
+		}
+		table.Rows[i] = metav1.TableRow{Cells: row, Object: runtime.RawExtension{Object: partialObjectMetadata(obj)}}
+	}
+	return table, nil
+}
+
+// typedList converts lists read through the dynamic client to their typed
+// form when client-go knows the kind, so they get their printer
+func typedList(list runtime.Object) runtime.Object {
+	u, ok := list.(*unstructured.UnstructuredList)
+	if !ok {
+		return list
+	}
+	typed, err := scheme.Scheme.New(u.GroupVersionKind())
+	if err != nil {
+		return list
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), typed); err != nil {
+		return list
+	}
+	return typed
+}
+
+// partialObjectMetadata is the metadata of a row without managedFields
+func partialObjectMetadata(obj metav1.Object) *metav1.PartialObjectMetadata {
+	partial := &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: metav1.SchemeGroupVersion.String(), Kind: "PartialObjectMetadata"},
+	}
+	partial.Name = obj.GetName()
+	partial.GenerateName = obj.GetGenerateName()
+	partial.Namespace = obj.GetNamespace()
+	partial.UID = obj.GetUID()
+	partial.ResourceVersion = obj.GetResourceVersion()
+	partial.Generation = obj.GetGeneration()
+	partial.CreationTimestamp = obj.GetCreationTimestamp()
+	partial.DeletionTimestamp = obj.GetDeletionTimestamp()
+	partial.Labels = obj.GetLabels()
+	partial.Annotations = obj.GetAnnotations()
+	partial.OwnerReferences = obj.GetOwnerReferences()
+	partial.Finalizers = obj.GetFinalizers()
+	return partial
+}
+
+// age is how long ago t was, like the AGE column of kubectl
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+func printPod(pod *corev1.Pod) []interface{} {
+	status, _ := getFormatStatus(pod)
+	restarts := 0
+	var lastRestart metav1.Time
+	for _, container := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		restarts += int(container.RestartCount)
+		if terminated := container.LastTerminationState.Terminated; terminated != nil && lastRestart.Before(&terminated.FinishedAt) {
+			lastRestart = terminated.FinishedAt
+		}
+	}
+	restartsCell := fmt.Sprintf("%d", restarts)
+	if restarts != 0 && !lastRestart.IsZero() {
+		restartsCell = fmt.Sprintf("%d (%s ago)", restarts, age(lastRestart))
+	}
+	readinessGates := "<none>"
+	if len(pod.Spec.ReadinessGates) > 0 {
+		ready := 0
+		for _, gate := range pod.Spec.ReadinessGates {
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == gate.ConditionType && condition.Status == corev1.ConditionTrue {
+					ready++
+					break
+				}
+			}
+		}
+		readinessGates = fmt.Sprintf("%d/%d", ready, len(pod.Spec.ReadinessGates))
+	}
+	return []interface{}{
+		pod.Name, status.Ready, status.Reason, restartsCell, age(pod.CreationTimestamp),
+		orNone(pod.Status.PodIP), orNone(pod.Spec.NodeName), orNone(pod.Status.NominatedNodeName), readinessGates,
+	}
+}
+
+func printDeployment(deployment *appsv1.Deployment) []interface{} {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	containers, images := containersAndImages(deployment.Spec.Template.Spec.Containers)
+	return []interface{}{
+		deployment.Name,
+		fmt.Sprintf("%d/%d", deployment.Status.ReadyReplicas, desired),
+		int64(deployment.Status.UpdatedReplicas),
+		int64(deployment.Status.AvailableReplicas),
+		age(deployment.CreationTimestamp),
+		containers, images, orNone(metav1.FormatLabelSelector(deployment.Spec.Selector)),
+	}
+}
+
+func printJob(job *batchv1.Job) []interface{} {
+	var completions string
+	switch {
+	case job.Spec.Completions != nil:
+		completions = fmt.Sprintf("%d/%d", job.Status.Succeeded, *job.Spec.Completions)
+	case job.Spec.Parallelism != nil && *job.Spec.Parallelism > 1:
+		completions = fmt.Sprintf("%d/1 of %d", job.Status.Succeeded, *job.Spec.Parallelism)
+	default:
+		completions = fmt.Sprintf("%d/1", job.Status.Succeeded)
+	}
+	var jobDuration string
+	switch {
+	case job.Status.StartTime == nil:
+	case job.Status.CompletionTime == nil:
+		jobDuration = age(*job.Status.StartTime)
+	default:
+		jobDuration = duration.HumanDuration(job.Status.CompletionTime.Sub(job.Status.StartTime.Time))
+	}
+	containers, images := containersAndImages(job.Spec.Template.Spec.Containers)
+	return []interface{}{
+		job.Name, completions, jobDuration, age(job.CreationTimestamp),
+		containers, images, orNone(metav1.FormatLabelSelector(job.Spec.Selector)),
+	}
+}
+
+// printCronJob prints batch/v1 and batch/v1beta1 cronjobs alike
+func printCronJob(obj metav1.Object, schedule string, suspend *bool, active int, lastSchedule *metav1.Time, jobSpec *batchv1.JobSpec) []interface{} {
+	suspendCell := "<unset>"
+	if suspend != nil {
+		suspendCell = fmt.Sprintf("%t", *suspend)
+		suspendCell = strings.ToUpper(suspendCell[:1]) + suspendCell[1:]
+	}
+	lastScheduleCell := "<none>"
+	if lastSchedule != nil {
+		lastScheduleCell = age(*lastSchedule)
+	}
+	containers, images := containersAndImages(jobSpec.Template.Spec.Containers)
+	return []interface{}{
+		obj.GetName(), schedule, suspendCell, int64(active), lastScheduleCell, age(obj.GetCreationTimestamp()),
+		containers, images, orNone(metav1.FormatLabelSelector(jobSpec.Selector)),
+	}
+}
+
+func printNode(node *corev1.Node) []interface{} {
+	status := "Unknown"
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			if condition.Status == corev1.ConditionTrue {
+				status = "Ready"
+			} else {
+				status = "NotReady"
+			}
+		}
+	}
+	if node.Spec.Unschedulable {
+		status += ",SchedulingDisabled"
+	}
+	roles := make([]string, 0)
+	for label, value := range node.Labels {
+		switch {
+		case strings.HasPrefix(label, "node-role.kubernetes.io/"):
+			if role := strings.TrimPrefix(label, "node-role.kubernetes.io/"); role != "" {
+				roles = append(roles, role)
+			}
+		case label == "kubernetes.io/role" && value != "":
+			roles = append(roles, value)
+		}
+	}
+	sort.Strings(roles)
+	var internalIP, externalIP string
+	for _, address := range node.Status.Addresses {
+		switch {
+		case address.Type == corev1.NodeInternalIP && internalIP == "":
+			internalIP = address.Address
+		case address.Type == corev1.NodeExternalIP && externalIP == "":
+			externalIP = address.Address
+		}
+	}
+	info := node.Status.NodeInfo
+	return []interface{}{
+		node.Name, status, orNone(strings.Join(roles, ",")), age(node.CreationTimestamp), info.KubeletVersion,
+		orNone(internalIP), orNone(externalIP), orNone(info.OSImage), orNone(info.KernelVersion), orNone(info.ContainerRuntimeVersion),
+	}
+}
+
+func printService(service *corev1.Service) []interface{} {
+	externalIPs := service.Spec.ExternalIPs
+	var externalIP string
+	switch service.Spec.Type {
+	case corev1.ServiceTypeExternalName:
+		externalIP = service.Spec.ExternalName
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				externalIPs = append(externalIPs, ingress.IP)
+			} else if ingress.Hostname != "" {
+				externalIPs = append(externalIPs, ingress.Hostname)
+			}
+		}
+		externalIP = strings.Join(externalIPs, ",")
+		if externalIP == "" {
+			externalIP = "<pending>"
+		}
+	default:
+		externalIP = strings.Join(externalIPs, ",")
+	}
+	ports := make([]string, len(service.Spec.Ports))
+	for i, port := range service.Spec.Ports {
+		if port.NodePort != 0 {
+			ports[i] = fmt.Sprintf("%d:%d/%s", port.Port, port.NodePort, port.Protocol)
+		} else {
+			ports[i] = fmt.Sprintf("%d/%s", port.Port, port.Protocol)
+		}
+	}
+	return []interface{}{
+		service.Name, string(service.Spec.Type), orNone(service.Spec.ClusterIP), orNone(externalIP),
+		orNone(strings.Join(ports, ",")), age(service.CreationTimestamp),
+		orNone(labels.FormatLabels(service.Spec.Selector)),
+	}
+}
+
+func printHorizontalPodAutoscaler(hpa *autoscalingv1.HorizontalPodAutoscaler) []interface{} {
+	targets := "<none>"
+	if hpa.Spec.TargetCPUUtilizationPercentage != nil {
+		current := "<unknown>"
+		if hpa.Status.CurrentCPUUtilizationPercentage != nil {
+			current = fmt.Sprintf("%d%%", *hpa.Status.CurrentCPUUtilizationPercentage)
+		}
+		targets = fmt.Sprintf("%s/%d%%", current, *hpa.Spec.TargetCPUUtilizationPercentage)
+	}
+	minPods := "<unset>"
+	if hpa.Spec.MinReplicas != nil {
+		minPods = fmt.Sprintf("%d", *hpa.Spec.MinReplicas)
+	}
+	return []interface{}{
+		hpa.Name, hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name, targets, minPods,
+		int64(hpa.Spec.MaxReplicas), int64(hpa.Status.CurrentReplicas), age(hpa.CreationTimestamp),
+	}
+}
+
+func printHorizontalPodAutoscalerV2(hpa *autoscalingv2.HorizontalPodAutoscaler) []interface{} {
+	minPods := "<unset>"
+	if hpa.Spec.MinReplicas != nil {
+		minPods = fmt.Sprintf("%d", *hpa.Spec.MinReplicas)
+	}
+	return []interface{}{
+		hpa.Name, hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name,
+		hpaTargets(hpa.Spec.Metrics, hpa.Status.CurrentMetrics), minPods,
+		int64(hpa.Spec.MaxReplicas), int64(hpa.Status.CurrentReplicas), age(hpa.CreationTimestamp),
+	}
+}
+
+// hpaTargets prints current/target of each metric like kubectl, the
+// statuses are in the order of the specs
+func hpaTargets(specs []autoscalingv2.MetricSpec, statuses []autoscalingv2.MetricStatus) string {
+	if len(specs) == 0 {
+		return "<none>"
+	}
+	const shown = 2
+	targets := make([]string, 0, len(specs))
+	for i, spec := range specs {
+		var status autoscalingv2.MetricStatus
+		if i < len(statuses) {
+			status = statuses[i]
+		}
+		var target autoscalingv2.MetricTarget
+		var current *autoscalingv2.MetricValueStatus
+		switch spec.Type {
+		case autoscalingv2.ResourceMetricSourceType:
+			if spec.Resource == nil {
+				continue
+			}
+			target = spec.Resource.Target
+			if status.Resource != nil {
+				current = &status.Resource.Current
+			}
+		case autoscalingv2.ContainerResourceMetricSourceType:
+			if spec.ContainerResource == nil {
+				continue
+			}
+			target = spec.ContainerResource.Target
+			if status.ContainerResource != nil {
+				current = &status.ContainerResource.Current
+			}
+		case autoscalingv2.PodsMetricSourceType:
+			if spec.Pods == nil {
+				continue
+			}
+			target = spec.Pods.Target
+			if status.Pods != nil {
+				current = &status.Pods.Current
+			}
+		case autoscalingv2.ObjectMetricSourceType:
+			if spec.Object == nil {
+				continue
+			}
+			target = spec.Object.Target
+			if status.Object != nil {
+				current = &status.Object.Current
+			}
+		case autoscalingv2.ExternalMetricSourceType:
+			if spec.External == nil {
+				continue
+			}
+			target = spec.External.Target
+			if status.External != nil {
+				current = &status.External.Current
+			}
+		default:
+			targets = append(targets, "<unknown type>")
+			continue
+		}
+		targets = append(targets, metricTarget(target, current, spec.Type))
+	}
+	if len(targets) > shown {
+		return fmt.Sprintf("%s + %d more...", strings.Join(targets[:shown], ", "), len(targets)-shown)
+	}
+	return strings.Join(targets, ", ")
+}
+
+func metricTarget(target autoscalingv2.MetricTarget, current *autoscalingv2.MetricValueStatus, metricType autoscalingv2.MetricSourceType) string {
+	currentCell := "<unknown>"
+	switch {
+	case target.AverageUtilization != nil || target.Type == autoscalingv2.UtilizationMetricType:
+		if current != nil && current.AverageUtilization != nil {
+			currentCell = fmt.Sprintf("%d%%", *current.AverageUtilization)
+		}
+		targetCell := "<auto>"
+		if target.AverageUtilization != nil {
+			targetCell = fmt.Sprintf("%d%%", *target.AverageUtilization)
+		}
+		return currentCell + "/" + targetCell
+	case target.AverageValue != nil:
+		if current != nil && current.AverageValue != nil {
+			currentCell = current.AverageValue.String()
+		}
+		// object and external metrics also have plain values, mark averages
+		if metricType == autoscalingv2.ObjectMetricSourceType || metricType == autoscalingv2.ExternalMetricSourceType {
+			return currentCell + "/" + target.AverageValue.String() + " (avg)"
+		}
+		return currentCell + "/" + target.AverageValue.String()
+	case target.Value != nil:
+		if current != nil && current.Value != nil {
+			currentCell = current.Value.String()
+		}
+		return currentCell + "/" + target.Value.String()
+	default:
+		return currentCell + "/<none>"
+	}
+}
+
+func containersAndImages(containers []corev1.Container) (string, string) {
+	names := make([]string, len(containers))
+	images := make([]string, len(containers))
+	for i, container := range containers {
+		names[i] = container.Name
+		images[i] = container.Image
+	}
+	return strings.Join(names, ","), strings.Join(images, ",")
+}
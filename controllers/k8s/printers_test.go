@@ -0,0 +1,240 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// created an hour ago, printed as age 60m
+var created = metav1.NewTime(time.Now().Add(-time.Hour))
+
+func objectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: "team-a", CreationTimestamp: created}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func columnNames(table *metav1.Table) []string {
+	names := make([]string, len(table.ColumnDefinitions))
+	for i, column := range table.ColumnDefinitions {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// unstructuredList is list as the dynamic client returns it
+func unstructuredList(t *testing.T, list runtime.Object, apiVersion, kind string) *unstructured.UnstructuredList {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &unstructured.UnstructuredList{}
+	u.SetUnstructuredContent(content)
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	return u
+}
+
+func TestPrintTable(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: objectMeta("web-1"),
+		Spec: corev1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: "10.0.0.1",
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				{Name: "sidecar", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}, RestartCount: 3},
+			},
+		},
+	}
+	deployment := appsv1.Deployment{
+		ObjectMeta: objectMeta("web"),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(3),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "web:1"}}}},
+		},
+		Status: appsv1.DeploymentStatus{ReadyReplicas: 2, UpdatedReplicas: 3, AvailableReplicas: 2},
+	}
+	cronJob := batchv1.CronJob{
+		ObjectMeta: objectMeta("backup"),
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 * * * *",
+			Suspend:  boolPtr(false),
+			JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "backup", Image: "backup:1"}}}},
+			}},
+		},
+	}
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", CreationTimestamp: created, Labels: map[string]string{
+			"node-role.kubernetes.io/worker":        "",
+			"node-role.kubernetes.io/control-plane": "",
+		}},
+		Spec: corev1.NodeSpec{Unschedulable: true},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "192.168.0.1"}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.23.1", OSImage: "Ubuntu", KernelVersion: "5.4", ContainerRuntimeVersion: "containerd://1.5"},
+		},
+	}
+	service := corev1.Service{
+		ObjectMeta: objectMeta("web"),
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeLoadBalancer,
+			ClusterIP: "10.96.0.10",
+			Ports:     []corev1.ServicePort{{Port: 80, NodePort: 30080, Protocol: corev1.ProtocolTCP}},
+			Selector:  map[string]string{"app": "web"},
+		},
+	}
+	hpa := autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: objectMeta("web"),
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MinReplicas:    int32Ptr(2),
+			MaxReplicas:    10,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{Name: corev1.ResourceCPU, Target: autoscalingv2.MetricTarget{
+					Type: autoscalingv2.UtilizationMetricType, AverageUtilization: int32Ptr(80),
+				}},
+			}, {
+				Type: autoscalingv2.PodsMetricSourceType,
+				Pods: &autoscalingv2.PodsMetricSource{Target: autoscalingv2.MetricTarget{
+					Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewQuantity(100, resource.DecimalSI),
+				}},
+			}},
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: 4,
+			CurrentMetrics: []autoscalingv2.MetricStatus{{
+				Type:     autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricStatus{Current: autoscalingv2.MetricValueStatus{AverageUtilization: int32Ptr(65)}},
+			}},
+		},
+	}
+	configMap := corev1.ConfigMap{ObjectMeta: objectMeta("settings")}
+
+	tests := []struct {
+		name    string
+		list    runtime.Object
+		wide    bool
+		columns []string
+		cells   []interface{}
+	}{
+		{"pod", &corev1.PodList{Items: []corev1.Pod{pod}}, false,
+			[]string{"Name", "Ready", "Status", "Restarts", "Age"},
+			[]interface{}{"web-1", "1/2", "CrashLoopBackOff", "3", "60m"}},
+		{"pod wide", &corev1.PodList{Items: []corev1.Pod{pod}}, true,
+			[]string{"Name", "Ready", "Status", "Restarts", "Age", "IP", "Node", "Nominated Node", "Readiness Gates"},
+			[]interface{}{"web-1", "1/2", "CrashLoopBackOff", "3", "60m", "10.0.0.1", "node-1", "<none>", "<none>"}},
+		{"deployment", &appsv1.DeploymentList{Items: []appsv1.Deployment{deployment}}, false,
+			[]string{"Name", "Ready", "Up-to-date", "Available", "Age"},
+			[]interface{}{"web", "2/3", int64(3), int64(2), "60m"}},
+		{"deployment wide", &appsv1.DeploymentList{Items: []appsv1.Deployment{deployment}}, true,
+			[]string{"Name", "Ready", "Up-to-date", "Available", "Age", "Containers", "Images", "Selector"},
+			[]interface{}{"web", "2/3", int64(3), int64(2), "60m", "app", "web:1", "app=web"}},
+		{"cronjob", &batchv1.CronJobList{Items: []batchv1.CronJob{cronJob}}, false,
+			[]string{"Name", "Schedule", "Suspend", "Active", "Last Schedule", "Age"},
+			[]interface{}{"backup", "0 * * * *", "False", int64(0), "<none>", "60m"}},
+		{"node wide", &corev1.NodeList{Items: []corev1.Node{node}}, true,
+			[]string{"Name", "Status", "Roles", "Age", "Version", "Internal-IP", "External-IP", "OS-Image", "Kernel-Version", "Container-Runtime"},
+			[]interface{}{"node-1", "Ready,SchedulingDisabled", "control-plane,worker", "60m", "v1.23.1", "192.168.0.1", "<none>", "Ubuntu", "5.4", "containerd://1.5"}},
+		{"service", &corev1.ServiceList{Items: []corev1.Service{service}}, false,
+			[]string{"Name", "Type", "Cluster-IP", "External-IP", "Port(s)", "Age"},
+			[]interface{}{"web", "LoadBalancer", "10.96.0.10", "<pending>", "80:30080/TCP", "60m"}},
+		{"dynamic deployment", unstructuredList(t, &appsv1.DeploymentList{Items: []appsv1.Deployment{deployment}}, "apps/v1", "DeploymentList"), false,
+			[]string{"Name", "Ready", "Up-to-date", "Available", "Age"},
+			[]interface{}{"web", "2/3", int64(3), int64(2), "60m"}},
+		{"dynamic autoscaling/v2 hpa", unstructuredList(t, &autoscalingv2.HorizontalPodAutoscalerList{Items: []autoscalingv2.HorizontalPodAutoscaler{hpa}}, "autoscaling/v2", "HorizontalPodAutoscalerList"), false,
+			[]string{"Name", "Reference", "Targets", "MinPods", "MaxPods", "Replicas", "Age"},
+			[]interface{}{"web", "Deployment/web", "65%/80%, <unknown>/100", "2", int64(10), int64(4), "60m"}},
+		{"default", &corev1.ConfigMapList{Items: []corev1.ConfigMap{configMap}}, true,
+			[]string{"Name", "Age"},
+			[]interface{}{"settings", "60m"}},
+		{"dynamic custom resource", unstructuredList(t, &corev1.ConfigMapList{Items: []corev1.ConfigMap{configMap}}, "example.com/v1", "WidgetList"), false,
+			[]string{"Name", "Age"},
+			[]interface{}{"settings", "60m"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := PrintTable(tt.list, tt.wide)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := columnNames(table); !reflect.DeepEqual(got, tt.columns) {
+				t.Errorf("columns = %v, want %v", got, tt.columns)
+			}
+			if len(table.Rows) != 1 {
+				t.Fatalf("got %d rows, want 1", len(table.Rows))
+			}
+			row := table.Rows[0]
+			if !reflect.DeepEqual(row.Cells, tt.cells) {
+				t.Errorf("cells = %#v, want %#v", row.Cells, tt.cells)
+			}
+			partial, ok := row.Object.Object.(*metav1.PartialObjectMetadata)
+			if !ok || partial.Name != tt.cells[0] {
+				t.Errorf("row object = %#v, want the metadata of %v", row.Object.Object, tt.cells[0])
+			}
+		})
+	}
+}
+
+func TestHPATargets(t *testing.T) {
+	utilization := func(target int32) autoscalingv2.MetricSpec {
+		return autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{Name: corev1.ResourceCPU, Target: autoscalingv2.MetricTarget{
+				Type: autoscalingv2.UtilizationMetricType, AverageUtilization: int32Ptr(target),
+			}},
+		}
+	}
+	external := autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ExternalMetricSourceType,
+		External: &autoscalingv2.ExternalMetricSource{Target: autoscalingv2.MetricTarget{
+			Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewQuantity(30, resource.DecimalSI),
+		}},
+	}
+	tests := []struct {
+		name     string
+		specs    []autoscalingv2.MetricSpec
+		statuses []autoscalingv2.MetricStatus
+		want     string
+	}{
+		{"none", nil, nil, "<none>"},
+		{"unknown current", []autoscalingv2.MetricSpec{utilization(80)}, nil, "<unknown>/80%"},
+		{"average of an external metric", []autoscalingv2.MetricSpec{external}, []autoscalingv2.MetricStatus{{
+			Type:     autoscalingv2.ExternalMetricSourceType,
+			External: &autoscalingv2.ExternalMetricStatus{Current: autoscalingv2.MetricValueStatus{AverageValue: resource.NewQuantity(12, resource.DecimalSI)}},
+		}}, "12/30 (avg)"},
+		{"more than two", []autoscalingv2.MetricSpec{utilization(50), utilization(60), utilization(70)}, nil,
+			"<unknown>/50%, <unknown>/60% + 1 more..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hpaTargets(tt.specs, tt.statuses); got != tt.want {
+				t.Errorf("hpaTargets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package k8s
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// probe requests must not hang on unreachable api servers
+	defaultProbeTimeout = 10 * time.Second
+)
+
+const (
+	ProbeErrorConfig  = "config"
+	ProbeErrorNetwork = "network"
+	ProbeErrorTLS     = "tls"
+	ProbeErrorAuth    = "auth"
+	ProbeErrorUnknown = "unknown"
+)
+
+type ClusterProbe struct {
+	Reachable bool          `json:"reachable"`
+	Version   string        `json:"version,omitempty"`
+	Platform  string        `json:"platform,omitempty"`
+	ErrorType string        `json:"errorType,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Rules     *RulesSummary `json:"rules,omitempty"`
+}
+
+type RulesSummary struct {
+	Namespace        string                            `json:"namespace"`
+	Incomplete       bool                              `json:"incomplete"`
+	EvaluationError  string                            `json:"evaluationError,omitempty"`
+	Permissions      map[string][]string               `json:"permissions"`
+	ResourceRules    []authorizationv1.ResourceRule    `json:"resourceRules"`
+	NonResourceRules []authorizationv1.NonResourceRule `json:"nonResourceRules"`
+}
+
+// ProbeCluster checks api server reachability, server version and what the
+// credentials in restConf are allowed to do in namespace.
+func ProbeCluster(ctx context.Context, restConf *rest.Config, namespace string) *ClusterProbe {
+	probe := &ClusterProbe{}
+
+	probeConf := rest.CopyConfig(restConf)
+	probeConf.Timeout = defaultProbeTimeout
+	clientSet, err := kubernetes.NewForConfig(probeConf)
+	if err != nil {
+		probe.ErrorType, probe.Error = ProbeErrorConfig, err.Error()
+		return probe
+	}
+
+	version, err := clientSet.Discovery().ServerVersion()
+	if err != nil {
+		probe.ErrorType, probe.Error = ClassifyProbeError(err), err.Error()
+		return probe
+	}
+	probe.Reachable = true
+	probe.Version = version.GitVersion
+	probe.Platform = version.Platform
+
+	review, err := clientSet.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		probe.ErrorType, probe.Error = ClassifyProbeError(err), err.Error()
+		return probe
+	}
+	probe.Rules = summarizeRules(namespace, review.Status)
+	return probe
+}
+
+// ClassifyProbeError tells tls, auth and network failures apart so callers
+// know whether to fix the CA, the credentials or the server address.
+func ClassifyProbeError(err error) string {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		netErr           net.Error
+	)
+	switch {
+	case errors.As(err, &unknownAuthority), errors.As(err, &hostname), errors.As(err, &invalid):
+		return ProbeErrorTLS
+	case strings.Contains(err.Error(), "x509:"), strings.Contains(err.Error(), "tls:"):
+		return ProbeErrorTLS
+	case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err):
+		return ProbeErrorAuth
+	case errors.As(err, &netErr):
+		return ProbeErrorNetwork
+	default:
+		return ProbeErrorUnknown
+	}
+}
+
+func summarizeRules(namespace string, status authorizationv1.SubjectRulesReviewStatus) *RulesSummary {
+	permissions := make(map[string][]string)
+	for _, rule := range status.ResourceRules {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				key := resource
+				if group != "" {
+					key = fmt.Sprintf("%s.%s", resource, group)
+				}
+				permissions[key] = mergeVerbs(permissions[key], rule.Verbs)
+			}
+		}
+	}
+	return &RulesSummary{
+		Namespace:        namespace,
+		Incomplete:       status.Incomplete,
+		EvaluationError:  status.EvaluationError,
+		Permissions:      permissions,
+		ResourceRules:    status.ResourceRules,
+		NonResourceRules: status.NonResourceRules,
+	}
+}
+
+func mergeVerbs(verbs []string, more []string) []string {
+	seen := make(map[string]bool, len(verbs))
+	for _, verb := range verbs {
+		seen[verb] = true
+	}
+	for _, verb := range more {
+		if !seen[verb] {
+			seen[verb] = true
+			verbs = append(verbs, verb)
+		}
+	}
+	sort.Strings(verbs)
+	return verbs
+}
@@ -0,0 +1,238 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// unknown kinds rediscover the cluster's api, but not more often than this
+const mapperMinReset = 30 * time.Second
+
+type clusterMapper struct {
+	mu      sync.Mutex
+	mapper  *restmapper.DeferredDiscoveryRESTMapper
+	shorts  meta.RESTMapper
+	resetAt time.Time
+}
+
+var restMappers = &sync.Map{}
+
+// ResourceMapping resolves a kind, plural, singular or short name such as
+// deploy, Deployment, deployments.apps or deployments.v1.apps through the
+// cluster's discovery, which is cached per cluster
+func ResourceMapping(clusterName, kind string) (*meta.RESTMapping, error) {
+	return resolveMapping(clusterName, func(mapper meta.RESTMapper) (*meta.RESTMapping, error) {
+		return mappingFor(mapper, kind)
+	})
+}
+
+// KindMapping resolves the apiVersion and kind of a manifest, an empty
+// version picks the preferred one
+func KindMapping(clusterName string, gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	return resolveMapping(clusterName, func(mapper meta.RESTMapper) (*meta.RESTMapping, error) {
+		if gvk.Version == "" {
+			return mapper.RESTMapping(gvk.GroupKind())
+		}
+		return mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	})
+}
+
+func resolveMapping(clusterName string, resolve func(meta.RESTMapper) (*meta.RESTMapping, error)) (*meta.RESTMapping, error) {
+	value, _ := restMappers.LoadOrStore(clusterName, &clusterMapper{})
+	m := value.(*clusterMapper)
+
+	m.mu.Lock()
+	if m.mapper == nil {
+		// discovered with the cluster's own credentials, never as a caller,
+		// so every caller resolves the same kinds
+		client, err := GetClient(clusterName)
+		if err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+		discovery := memory.NewMemCacheClient(client.ClientV1.Discovery())
+		m.mapper = restmapper.NewDeferredDiscoveryRESTMapper(discovery)
+		m.shorts = restmapper.NewShortcutExpander(m.mapper, discovery)
+		m.resetAt = time.Now()
+	}
+	mapper := m.shorts
+	m.mu.Unlock()
+
+	mapping, err := resolve(mapper)
+	if meta.IsNoMatchError(err) {
+		// the kind may belong to a crd installed after the last discovery
+		m.mu.Lock()
+		reset := time.Since(m.resetAt) > mapperMinReset
+		if reset {
+			m.mapper.Reset()
+			m.resetAt = time.Now()
+		}
+		m.mu.Unlock()
+		if reset {
+			mapping, err = resolve(mapper)
+		}
+	}
+	return mapping, err
+}
+
+// mappingFor resolves kind like kubectl does, as a resource first and then as a kind
+func mappingFor(mapper meta.RESTMapper, kind string) (*meta.RESTMapping, error) {
+	fullySpecifiedGVR, groupResource := schema.ParseResourceArg(strings.ToLower(kind))
+	gvk := schema.GroupVersionKind{}
+	if fullySpecifiedGVR != nil {
+		gvk, _ = mapper.KindFor(*fullySpecifiedGVR)
+	}
+	if gvk.Empty() {
+		gvk, _ = mapper.KindFor(groupResource.WithVersion(""))
+	}
+	if !gvk.Empty() {
+		return mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+
+	fullySpecifiedGVK, groupKind := schema.ParseKindArg(kind)
+	if fullySpecifiedGVK == nil {
+		gvk := groupKind.WithVersion("")
+		fullySpecifiedGVK = &gvk
+	}
+	if !fullySpecifiedGVK.Empty() {
+		if mapping, err := mapper.RESTMapping(fullySpecifiedGVK.GroupKind(), fullySpecifiedGVK.Version); err == nil {
+			return mapping, nil
+		}
+	}
+	mapping, err := mapper.RESTMapping(groupKind)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, &meta.NoResourceMatchError{PartialResource: groupResource.WithVersion("")}
+		}
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// ResourceName is how rules name the resource of mapping, see GroupResourceName
+func ResourceName(mapping *meta.RESTMapping) string {
+	return GroupResourceName(mapping.Resource.Group, mapping.Resource.Resource)
+}
+
+// RemoveResourceMapper drops the cached discovery of the cluster
+func RemoveResourceMapper(clusterName string) {
+	restMappers.Delete(clusterName)
+}
+
+// ResourceOperation reads and writes one resource type through the dynamic client
+type ResourceOperation struct {
+	dyn     dynamic.Interface
+	Mapping *meta.RESTMapping
+}
+
+func NewResourceOperation(dyn dynamic.Interface, mapping *meta.RESTMapping) *ResourceOperation {
+	return &ResourceOperation{dyn: dyn, Mapping: mapping}
+}
+
+func (o *ResourceOperation) Namespaced() bool {
+	return o.Mapping.Scope.Name() == meta.RESTScopeNameNamespace
+}
+
+// resource scopes the client to namespace, which cluster-scoped kinds ignore
+// and namespaced kinds require unless allNamespaces
+func (o *ResourceOperation) resource(namespace string, allNamespaces bool) (dynamic.ResourceInterface, error) {
+	if !o.Namespaced() {
+		return o.dyn.Resource(o.Mapping.Resource), nil
+	}
+	if namespace == "" && !allNamespaces {
+		return nil, fmt.Errorf("namespace is required for %s", o.Mapping.Resource.Resource)
+	}
+	return o.dyn.Resource(o.Mapping.Resource).Namespace(namespace), nil
+}
+
+func (o *ResourceOperation) List(ctx context.Context, namespace string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	resource, err := o.resource(namespace, true)
+	if err != nil {
+		return nil, err
+	}
+	return resource.List(ctx, opts)
+}
+
+func (o *ResourceOperation) Get(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	resource, err := o.resource(namespace, false)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Get(ctx, name, metav1.GetOptions{})
+}
+
+// Create uses the namespace of obj when namespace is empty
+func (o *ResourceOperation) Create(ctx context.Context, namespace string, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+	if err := o.prepare(obj, namespace, obj.GetName()); err != nil {
+		return nil, err
+	}
+	resource, err := o.resource(namespace, false)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Create(ctx, obj, metav1.CreateOptions{})
+}
+
+func (o *ResourceOperation) Update(ctx context.Context, namespace, name string, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if err := o.prepare(obj, namespace, name); err != nil {
+		return nil, err
+	}
+	resource, err := o.resource(namespace, false)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Update(ctx, obj, metav1.UpdateOptions{})
+}
+
+func (o *ResourceOperation) Patch(ctx context.Context, namespace, name string, patchType types.PatchType, data []byte, opts metav1.PatchOptions) (*unstructured.Unstructured, error) {
+	resource, err := o.resource(namespace, false)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Patch(ctx, name, patchType, data, opts)
+}
+
+func (o *ResourceOperation) Delete(ctx context.Context, namespace, name string) error {
+	resource, err := o.resource(namespace, false)
+	if err != nil {
+		return err
+	}
+	return resource.Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// prepare fills apiVersion, kind, namespace and name of obj from the route
+// and rejects a body of another kind
+func (o *ResourceOperation) prepare(obj *unstructured.Unstructured, namespace, name string) error {
+	gvk := o.Mapping.GroupVersionKind
+	if obj.GetKind() != "" && obj.GetKind() != gvk.Kind {
+		return fmt.Errorf("body kind %s does not match %s", obj.GetKind(), gvk.Kind)
+	}
+	if obj.GetAPIVersion() == "" {
+		obj.SetAPIVersion(gvk.GroupVersion().String())
+	}
+	obj.SetKind(gvk.Kind)
+	if name != "" {
+		obj.SetName(name)
+	}
+	if o.Namespaced() {
+		obj.SetNamespace(namespace)
+	} else {
+		obj.SetNamespace("")
+	}
+	return nil
+}
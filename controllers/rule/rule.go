@@ -0,0 +1,69 @@
+package rule
+
+import (
+	"github.com/mizhexiaoxiao/k8s-api-service/app"
+	"github.com/mizhexiaoxiao/k8s-api-service/models"
+)
+
+type ListQuery struct {
+	app.PageInfo
+	AppKey string `form:"appKey"`
+}
+
+func Create(data models.RuleModel) (models.RuleModel, error) {
+	if models.DB == nil {
+		return data, models.ErrNoDatabase
+	}
+	data.ID = 0
+	if data.Effect == "" {
+		data.Effect = models.RuleAllow
+	}
+	err := models.DB.Create(&data).Error
+	return data, err
+}
+
+// Update replaces every field of the rule, so lists can be emptied
+func Update(id int, data models.RuleModel) (err error) {
+	if models.DB == nil {
+		return models.ErrNoDatabase
+	}
+	if _, err = Get(id); err != nil {
+		return err
+	}
+	if data.Effect == "" {
+		data.Effect = models.RuleAllow
+	}
+	return models.DB.Model(&models.RuleModel{}).Where("id = ?", id).
+		Select("app_key", "effect", "clusters", "namespaces", "resources", "verbs").
+		Updates(&data).Error
+}
+
+func List(q ListQuery) (rules []*models.RuleModel, total int64, err error) {
+	if models.DB == nil {
+		return nil, 0, models.ErrNoDatabase
+	}
+	tx := models.DB.Model(&models.RuleModel{})
+	if q.AppKey != "" {
+		tx = tx.Where("app_key = ?", q.AppKey)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id").Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&rules).Error
+	return rules, total, err
+}
+
+func Get(id int) (rule models.RuleModel, err error) {
+	if models.DB == nil {
+		return rule, models.ErrNoDatabase
+	}
+	err = models.DB.First(&rule, id).Error
+	return rule, err
+}
+
+func Delete(id int) (err error) {
+	if models.DB == nil {
+		return models.ErrNoDatabase
+	}
+	return models.DB.Unscoped().Delete(&models.RuleModel{}, id).Error
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/mizhexiaoxiao/k8s-api-service/app"
 	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"github.com/mizhexiaoxiao/k8s-api-service/controllers/cluster"
 	"github.com/mizhexiaoxiao/k8s-api-service/models"
 	"github.com/mizhexiaoxiao/k8s-api-service/routers"
 )
@@ -18,6 +19,7 @@ func init() {
 }
 
 func main() {
+	cluster.StartMonitor()
 	routersInit := routers.InitRouter()
 	server := &http.Server{
 		Addr:         config.AppAddr(),
@@ -0,0 +1,38 @@
+package models
+
+const (
+	AuditEventRequest      = "request"
+	AuditEventSessionStart = "session_start"
+	AuditEventSessionEnd   = "session_end"
+)
+
+type AuditModel struct {
+	Model
+	Audit
+}
+
+// Audit is one mutating api call or one end of an interactive session
+type Audit struct {
+	AppKey      string `json:"appKey" gorm:"index"` // 调用方appKey，token用户为user:<name>
+	SourceIP    string `json:"sourceIP"`
+	Event       string `json:"event" gorm:"index"`
+	SessionID   string `json:"sessionID,omitempty" gorm:"index"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Cluster     string `json:"cluster" gorm:"index"`
+	Namespace   string `json:"namespace"`
+	Resource    string `json:"resource"`
+	Verb        string `json:"verb"`
+	RequestBody string `json:"requestBody" gorm:"type:text"`
+	StatusCode  int    `json:"statusCode"`
+	Error       string `json:"error"`
+	Latency     int64  `json:"latency"` // 毫秒
+}
+
+// RecordAudit stores an audit entry, ErrNoDatabase on the file backend
+func RecordAudit(entry *AuditModel) error {
+	if DB == nil {
+		return ErrNoDatabase
+	}
+	return DB.Create(entry).Error
+}
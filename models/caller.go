@@ -0,0 +1,146 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCallerDisabled = errors.New("caller is disabled")
+	ErrCallerExpired  = errors.New("caller has expired")
+)
+
+type CallerModel struct {
+	Model
+	Caller
+}
+
+// Caller is an api consumer stored in the database. Only salted hashes of its
+// secrets are kept, the plaintext is returned once on creation and rotation.
+// hmac callers sign their requests, checking a signature needs the secret, so
+// theirs are also kept sealed with the master key.
+type Caller struct {
+	AppKey     string     `json:"appKey" gorm:"unique" binding:"required"`
+	Desc       string     `json:"desc"`
+	Mode       string     `json:"mode"` // header或hmac，同配置文件中的caller.<appKey>.mode
+	Enabled    bool       `json:"enabled"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+
+	SecretSalt   string `json:"-"`
+	SecretHash   string `json:"-"`
+	SecretSealed string `json:"-"`
+	// the secret replaced by the last rotation stays valid until PreviousExpiresAt
+	PreviousSecretSalt   string     `json:"-"`
+	PreviousSecretHash   string     `json:"-"`
+	PreviousSecretSealed string     `json:"-"`
+	PreviousExpiresAt    *time.Time `json:"previousSecretExpiresAt"`
+}
+
+// NewSecret generates a random secret and stores its salted hash on the
+// caller, hmac callers also store it sealed
+func (c *Caller) NewSecret() (string, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	salt, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	c.SecretSalt, c.SecretHash, c.SecretSealed = salt, hashSecret(salt, secret), ""
+	if c.Mode == config.CallerModeHMAC {
+		if c.SecretSealed, err = sealSecret(secret); err != nil {
+			return "", err
+		}
+	}
+	return secret, nil
+}
+
+// Verify checks secret against the current and, during the grace period,
+// the previous secret of the caller
+func (c *Caller) Verify(secret string, now time.Time) (bool, error) {
+	if err := c.usable(now); err != nil {
+		return false, err
+	}
+	if hmac.Equal([]byte(hashSecret(c.SecretSalt, secret)), []byte(c.SecretHash)) {
+		return true, nil
+	}
+	if c.PreviousSecretHash != "" && c.PreviousExpiresAt != nil && now.Before(*c.PreviousExpiresAt) {
+		return hmac.Equal([]byte(hashSecret(c.PreviousSecretSalt, secret)), []byte(c.PreviousSecretHash)), nil
+	}
+	return false, nil
+}
+
+// SigningSecrets returns the secrets an hmac caller may sign with, the
+// previous one only during the grace period
+func (c *Caller) SigningSecrets(now time.Time) ([]string, error) {
+	if err := c.usable(now); err != nil {
+		return nil, err
+	}
+	secret, err := openSecret(c.SecretSealed)
+	if err != nil {
+		return nil, err
+	}
+	secrets := []string{secret}
+	if c.PreviousSecretSealed != "" && c.PreviousExpiresAt != nil && now.Before(*c.PreviousExpiresAt) {
+		previous, err := openSecret(c.PreviousSecretSealed)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, previous)
+	}
+	return secrets, nil
+}
+
+func (c *Caller) usable(now time.Time) error {
+	if !c.Enabled {
+		return ErrCallerDisabled
+	}
+	if c.ExpiresAt != nil && now.After(*c.ExpiresAt) {
+		return ErrCallerExpired
+	}
+	return nil
+}
+
+// CallerByAppKey looks a caller up, gorm.ErrRecordNotFound when there is none
+// and ErrNoDatabase on the file backend
+func CallerByAppKey(appKey string) (caller CallerModel, err error) {
+	if DB == nil {
+		return caller, ErrNoDatabase
+	}
+	// Find rather than First, a missing caller is the common case for config callers
+	tx := DB.Where("app_key = ?", appKey).Limit(1).Find(&caller)
+	if tx.Error == nil && tx.RowsAffected == 0 {
+		return caller, gorm.ErrRecordNotFound
+	}
+	return caller, tx.Error
+}
+
+// TouchCaller sets the last used time of a caller
+func TouchCaller(id uint, usedAt time.Time) error {
+	if DB == nil {
+		return ErrNoDatabase
+	}
+	return DB.Model(&CallerModel{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+func hashSecret(salt, secret string) string {
+	sum := sha256.Sum256([]byte(salt + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
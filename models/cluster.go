@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/datatypes"
 )
 
@@ -10,8 +12,32 @@ type ClusterModel struct {
 }
 
 type Cluster struct {
-	Name    string         `json:"name" gorm:"unique"`
-	Desc    string         `json:"desc"`
-	Context datatypes.JSON `json:"context"`
-	State   bool           `json:"state"`
+	Name       string         `json:"name" gorm:"unique"`
+	Desc       string         `json:"desc"`
+	Context    datatypes.JSON `json:"context"`
+	KeyVersion int            `json:"keyVersion"`
+	State      bool           `json:"state"`
+	// ClientOptions tune the client built for this cluster, zero values fall back to config defaults
+	ClientOptions ClientOptions `json:"clientOptions" gorm:"embedded;embeddedPrefix:client_"`
+	ClusterHealth
+}
+
+type ClientOptions struct {
+	QPS           float32 `json:"qps" binding:"gte=0"`
+	Burst         int     `json:"burst" binding:"gte=0"`
+	Timeout       int     `json:"timeout" binding:"gte=0"` // 秒
+	ProxyURL      string  `json:"proxyURL" binding:"omitempty,url"`
+	TLSServerName string  `json:"tlsServerName"`
+	Insecure      bool    `json:"insecure"`
+	// Impersonate makes every request act as its caller, leaving authorization to the cluster's RBAC
+	Impersonate bool `json:"impersonate"`
+}
+
+// ClusterHealth is maintained by the cluster health monitor
+type ClusterHealth struct {
+	LastCheckedAt *time.Time `json:"lastCheckedAt"`
+	LastError     string     `json:"lastError"`
+	Version       string     `json:"version"`
+	NodesReady    int        `json:"nodesReady"`
+	NodesTotal    int        `json:"nodesTotal"`
 }
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"sigs.k8s.io/yaml"
+)
+
+// ClusterFile is the format of a cluster yaml file of the file backend
+type ClusterFile struct {
+	Name          string        `json:"name"`
+	Desc          string        `json:"desc"`
+	ClientOptions ClientOptions `json:"clientOptions"`
+	// KubeConfig is a kubeconfig document, its current-context is used
+	KubeConfig string `json:"kubeconfig"`
+}
+
+// fileClusterStore serves clusters loaded from a directory of yaml files.
+// It is read-only, only health results are kept in memory.
+type fileClusterStore struct {
+	mu       sync.RWMutex
+	clusters []ClusterModel
+}
+
+// NewFileClusterStore loads every *.yaml and *.yml file of dir, ids are
+// assigned in file name order
+func NewFileClusterStore(dir string) (ClusterStore, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	store := &fileClusterStore{}
+	names := make(map[string]string)
+	for i, file := range files {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var clusterFile ClusterFile
+		if err := yaml.UnmarshalStrict(raw, &clusterFile); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if clusterFile.Name == "" {
+			return nil, fmt.Errorf("%s: cluster name is empty", file)
+		}
+		if other, ok := names[clusterFile.Name]; ok {
+			return nil, fmt.Errorf("%s: cluster %q is already defined in %s", file, clusterFile.Name, other)
+		}
+		names[clusterFile.Name] = file
+		context, err := yaml.YAMLToJSON([]byte(clusterFile.KubeConfig))
+		if err != nil {
+			return nil, fmt.Errorf("%s: kubeconfig: %v", file, err)
+		}
+		cluster := ClusterModel{Cluster: Cluster{
+			Name:          clusterFile.Name,
+			Desc:          clusterFile.Desc,
+			Context:       datatypes.JSON(context),
+			ClientOptions: clusterFile.ClientOptions,
+		}}
+		cluster.ID = uint(i + 1)
+		store.clusters = append(store.clusters, cluster)
+	}
+	return store, nil
+}
+
+func (s *fileClusterStore) Create(cluster *ClusterModel) error {
+	return ErrReadOnly
+}
+
+func (s *fileClusterStore) Update(id int, cluster ClusterModel) error {
+	return ErrReadOnly
+}
+
+func (s *fileClusterStore) UpdateColumns(id int, columns map[string]interface{}) error {
+	return ErrReadOnly
+}
+
+func (s *fileClusterStore) UpdateHealth(id int, state bool, health ClusterHealth) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.clusters {
+		if int(s.clusters[i].ID) == id {
+			s.clusters[i].State = state
+			s.clusters[i].ClusterHealth = health
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (s *fileClusterStore) Get(id int) (ClusterModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, cluster := range s.clusters {
+		if int(cluster.ID) == id {
+			return cluster, nil
+		}
+	}
+	return ClusterModel{}, gorm.ErrRecordNotFound
+}
+
+func (s *fileClusterStore) GetByName(name string) (ClusterModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, cluster := range s.clusters {
+		if cluster.Name == name {
+			return cluster, nil
+		}
+	}
+	return ClusterModel{}, gorm.ErrRecordNotFound
+}
+
+func (s *fileClusterStore) List(offset, limit int) ([]*ClusterModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	clusters := make([]*ClusterModel, 0)
+	for i := offset; i < len(s.clusters) && (limit < 0 || len(clusters) < limit); i++ {
+		cluster := s.clusters[i]
+		clusters = append(clusters, &cluster)
+	}
+	return clusters, nil
+}
+
+func (s *fileClusterStore) Count() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.clusters)), nil
+}
+
+func (s *fileClusterStore) Delete(id int) error {
+	return ErrReadOnly
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// healthColumns are owned by the health monitor and never written from the api
+var healthColumns = []string{"state", "last_checked_at", "last_error", "version", "nodes_ready", "nodes_total"}
+
+// gormClusterStore keeps clusters in a sql database, used by the postgres and sqlite backends
+type gormClusterStore struct {
+	db *gorm.DB
+}
+
+func NewGormClusterStore(db *gorm.DB) ClusterStore {
+	return &gormClusterStore{db: db}
+}
+
+func (s *gormClusterStore) Create(cluster *ClusterModel) error {
+	err := s.db.Model(&ClusterModel{}).Create(cluster).Error
+	if isUniqueViolation(err) {
+		return ErrDuplicateName
+	}
+	return err
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation of
+// postgres or sqlite
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	return false
+}
+
+func (s *gormClusterStore) Update(id int, cluster ClusterModel) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&ClusterModel{}).Where("id = ?", id).Omit(healthColumns...).Updates(&cluster).Error; err != nil {
+			return err
+		}
+		if len(cluster.Context) == 0 {
+			return nil
+		}
+		// Updates skips zero values, a plaintext context must reset the key version
+		return tx.Model(&ClusterModel{}).Where("id = ?", id).Update("key_version", cluster.KeyVersion).Error
+	})
+}
+
+func (s *gormClusterStore) UpdateColumns(id int, columns map[string]interface{}) error {
+	return s.db.Model(&ClusterModel{}).Where("id = ?", id).Updates(columns).Error
+}
+
+func (s *gormClusterStore) UpdateHealth(id int, state bool, health ClusterHealth) error {
+	return s.UpdateColumns(id, map[string]interface{}{
+		"state":           state,
+		"last_checked_at": health.LastCheckedAt,
+		"last_error":      health.LastError,
+		"version":         health.Version,
+		"nodes_ready":     health.NodesReady,
+		"nodes_total":     health.NodesTotal,
+	})
+}
+
+func (s *gormClusterStore) Get(id int) (cluster ClusterModel, err error) {
+	err = s.db.Model(&ClusterModel{}).Where("id = ?", id).First(&cluster).Error
+	return
+}
+
+func (s *gormClusterStore) GetByName(name string) (cluster ClusterModel, err error) {
+	err = s.db.Model(&ClusterModel{}).Where("name = ?", name).First(&cluster).Error
+	return
+}
+
+func (s *gormClusterStore) List(offset, limit int) (clusters []*ClusterModel, err error) {
+	err = s.db.Model(&ClusterModel{}).Offset(offset).Limit(limit).Find(&clusters).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+func (s *gormClusterStore) Count() (count int64, err error) {
+	err = s.db.Model(&ClusterModel{}).Count(&count).Error
+	return
+}
+
+func (s *gormClusterStore) Delete(id int) error {
+	//软删除
+	//return s.db.Delete(&ClusterModel{}, id).Error
+	//硬删除
+	return s.db.Unscoped().Delete(&ClusterModel{}, id).Error
+}
@@ -1,6 +1,62 @@
 package metadata
 
-type CommonQueryParameter struct {
-	NameSpace     string `form:"namespace" binding:"required"`
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// ListParameter are the list options of cluster-scoped lists
+type ListParameter struct {
 	LabelSelector string `form:"labelSelector"`
+	FieldSelector string `form:"fieldSelector"`
+	Limit         int64  `form:"limit" binding:"omitempty,min=1"`
+	Continue      string `form:"continue"`                                    // 上一页返回的continue
+	Output        string `form:"output" binding:"omitempty,oneof=table wide"` // table或wide时返回kubectl get的表格
+}
+
+// NamespaceListParameter lists one namespace, an empty namespace or
+// allNamespaces lists all of them
+type NamespaceListParameter struct {
+	Namespace     string `form:"namespace"`
+	AllNamespaces bool   `form:"allNamespaces"`
+	ListParameter
+}
+
+type CommonQueryParameter struct {
+	NameSpace     string `form:"namespace" binding:"required_without=AllNamespaces"`
+	AllNamespaces bool   `form:"allNamespaces"`
+	ListParameter
+}
+
+func (p ListParameter) ListOptions() metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector: p.LabelSelector,
+		FieldSelector: p.FieldSelector,
+		Limit:         p.Limit,
+		Continue:      p.Continue,
+	}
+}
+
+// ListNamespace is the namespace to list, empty for all namespaces
+func (p CommonQueryParameter) ListNamespace() string {
+	if p.AllNamespaces {
+		return ""
+	}
+	return p.NameSpace
+}
+
+// ListNamespace is the namespace to list, empty for all namespaces
+func (p NamespaceListParameter) ListNamespace() string {
+	if p.AllNamespaces {
+		return ""
+	}
+	return p.Namespace
+}
+
+// DryRunParameter previews a write without persisting it
+type DryRunParameter struct {
+	DryRun bool `form:"dryRun"`
+}
+
+// PreconditionParameter rejects a write when the object is no longer at
+// the resourceVersion the caller read
+type PreconditionParameter struct {
+	ResourceVersion string `form:"resourceVersion"`
 }
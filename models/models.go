@@ -1,14 +1,17 @@
 package models
 
 import (
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/mizhexiaoxiao/k8s-api-service/config"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// DB is nil when the file storage backend is used
 var DB *gorm.DB
 
 type Model struct {
@@ -18,17 +21,30 @@ type Model struct {
 	DeletedAt gorm.DeletedAt `json:"deleteAt" gorm:"index"`
 }
 
-// Setup initializes the database instance
+// Setup initializes the configured storage backend
 func Setup() {
-	log.Println("Setting up database connection")
+	driver := config.StorageDriver()
+	log.Printf("Setting up %s storage backend", driver)
 	var err error
 
-	dsn := config.DBdsn()
-
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	switch driver {
+	case config.StoragePostgres:
+		DB, err = gorm.Open(postgres.Open(config.DBdsn()), &gorm.Config{})
+	case config.StorageSQLite:
+		DB, err = gorm.Open(sqlite.Open(config.StorageSQLitePath()), &gorm.Config{})
+	case config.StorageFile:
+		Clusters, err = NewFileClusterStore(config.StorageFileDir())
+		if err != nil {
+			log.Fatalf("models.Setup err: %v", err)
+		}
+		return
+	default:
+		err = fmt.Errorf("unknown storage driver %q", driver)
+	}
 	if err != nil {
 		log.Fatalf("models.Setup err: %v", err)
 	}
 
-	DB.AutoMigrate(&ClusterModel{})
+	DB.AutoMigrate(&ClusterModel{}, &RuleModel{}, &AuditModel{}, &CallerModel{})
+	Clusters = NewGormClusterStore(DB)
 }
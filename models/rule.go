@@ -0,0 +1,69 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	RuleAllow = "allow"
+	RuleDeny  = "deny"
+)
+
+type RuleModel struct {
+	Model
+	Rule
+}
+
+// Rule grants (or denies) a subject verbs on resources of matching clusters
+// and namespaces. Every list holds glob patterns, empty lists match nothing.
+type Rule struct {
+	// AppKey is the subject, an appKey, user:<name> or group:<name> of token users
+	AppKey     string     `json:"appKey" gorm:"index" binding:"required"`
+	Effect     string     `json:"effect" binding:"omitempty,oneof=allow deny"` // 默认allow
+	Clusters   StringList `json:"clusters" gorm:"type:text"`
+	Namespaces StringList `json:"namespaces" gorm:"type:text"`
+	Resources  StringList `json:"resources" gorm:"type:text"`
+	Verbs      StringList `json:"verbs" gorm:"type:text"`
+}
+
+// StringList is stored as a comma separated column
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	for _, item := range l {
+		if strings.Contains(item, ",") {
+			return nil, fmt.Errorf("%q must not contain a comma", item)
+		}
+	}
+	return strings.Join(l, ","), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal StringList value:", value))
+	}
+	*l = nil
+	if s != "" {
+		*l = strings.Split(s, ",")
+	}
+	return nil
+}
+
+// RulesBySubjects returns the rules of any of subjects stored in the database
+func RulesBySubjects(subjects []string) (rules []RuleModel, err error) {
+	if DB == nil {
+		return nil, ErrNoDatabase
+	}
+	err = DB.Where("app_key IN ?", subjects).Order("id").Find(&rules).Error
+	return rules, err
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mizhexiaoxiao/k8s-api-service/config"
+	"gorm.io/datatypes"
+)
+
+// sealed contexts are "gcm:<nonce>:<ciphertext>"
+const gcmPrefix = "gcm:"
+
+var ErrNoCryptoKey = errors.New("crypto.keys is not configured")
+
+// SealContext encrypts cluster.Context with the current master key. The
+// ciphertext is kept as a json string so the column stays valid json.
+func SealContext(cluster *Cluster) error {
+	version := config.CryptoKeyVersion()
+	if version == 0 || len(cluster.Context) == 0 {
+		cluster.KeyVersion = 0
+		return nil
+	}
+	gcm, err := masterCipher(version)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	encrypted := gcm.Seal(nil, nonce, cluster.Context, keyVersionData(version))
+	sealed, err := json.Marshal(gcmPrefix + base64.URLEncoding.EncodeToString(nonce) + ":" +
+		base64.URLEncoding.EncodeToString(encrypted))
+	if err != nil {
+		return err
+	}
+	cluster.Context = datatypes.JSON(sealed)
+	cluster.KeyVersion = version
+	return nil
+}
+
+// OpenContext returns the plaintext kubeconfig json of cluster
+func OpenContext(cluster Cluster) ([]byte, error) {
+	if cluster.KeyVersion == 0 {
+		return cluster.Context, nil
+	}
+	var sealed string
+	if err := json.Unmarshal(cluster.Context, &sealed); err != nil {
+		return nil, fmt.Errorf("cluster %s context is not sealed: %v", cluster.Name, err)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(sealed, gcmPrefix), ":", 2)
+	if !strings.HasPrefix(sealed, gcmPrefix) || len(parts) != 2 {
+		return nil, fmt.Errorf("cluster %s context is malformed", cluster.Name)
+	}
+	nonce, err := base64.URLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := base64.URLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := masterCipher(cluster.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("cluster %s context is malformed", cluster.Name)
+	}
+	decrypted, err := gcm.Open(nil, nonce, encrypted, keyVersionData(cluster.KeyVersion))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt cluster %s context failed, wrong key or tampered data: %v", cluster.Name, err)
+	}
+	return decrypted, nil
+}
+
+// sealSecret encrypts a caller secret with the current master key as
+// "<version>:<nonce>:<ciphertext>"
+func sealSecret(secret string) (string, error) {
+	version := config.CryptoKeyVersion()
+	if version == 0 {
+		return "", ErrNoCryptoKey
+	}
+	gcm, err := masterCipher(version)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	encrypted := gcm.Seal(nil, nonce, []byte(secret), keyVersionData(version))
+	return strconv.Itoa(version) + ":" + base64.URLEncoding.EncodeToString(nonce) + ":" +
+		base64.URLEncoding.EncodeToString(encrypted), nil
+}
+
+// openSecret returns the plaintext of a secret sealed with sealSecret
+func openSecret(sealed string) (string, error) {
+	parts := strings.SplitN(sealed, ":", 3)
+	if len(parts) != 3 {
+		return "", errors.New("sealed secret is malformed")
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", errors.New("sealed secret is malformed")
+	}
+	nonce, err := base64.URLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := base64.URLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+	gcm, err := masterCipher(version)
+	if err != nil {
+		return "", err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return "", errors.New("sealed secret is malformed")
+	}
+	decrypted, err := gcm.Open(nil, nonce, encrypted, keyVersionData(version))
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret failed, wrong key or tampered data: %v", err)
+	}
+	return string(decrypted), nil
+}
+
+// masterCipher is the aes-256-gcm cipher of a master key version
+func masterCipher(version int) (cipher.AEAD, error) {
+	key, err := masterKey(version)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyVersionData binds a ciphertext to its key version, a row whose
+// key_version was changed no longer opens
+func keyVersionData(version int) []byte {
+	return []byte(strconv.Itoa(version))
+}
+
+// masterKey derives an aes-256 key from the configured passphrase
+func masterKey(version int) ([]byte, error) {
+	passphrase := config.CryptoKey(version)
+	if passphrase == "" {
+		return nil, fmt.Errorf("crypto key version %d is not configured", version)
+	}
+	key := sha256.Sum256([]byte(passphrase))
+	return key[:], nil
+}
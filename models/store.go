@@ -0,0 +1,29 @@
+package models
+
+import "errors"
+
+var ErrReadOnly = errors.New("storage backend is read-only")
+
+// ErrDuplicateName is returned when a cluster with the same name exists
+var ErrDuplicateName = errors.New("cluster name already exists")
+
+// ErrNoDatabase is returned by database only features on the file backend
+var ErrNoDatabase = errors.New("feature requires the postgres or sqlite storage backend")
+
+// Clusters is the cluster store of the configured storage backend
+var Clusters ClusterStore
+
+type ClusterStore interface {
+	Create(cluster *ClusterModel) error
+	// Update writes the non-zero fields of cluster, health columns are never written
+	Update(id int, cluster ClusterModel) error
+	// UpdateColumns writes the given columns including zero values
+	UpdateColumns(id int, columns map[string]interface{}) error
+	UpdateHealth(id int, state bool, health ClusterHealth) error
+	Get(id int) (ClusterModel, error)
+	GetByName(name string) (ClusterModel, error)
+	// List returns all clusters from offset on when limit is negative
+	List(offset, limit int) ([]*ClusterModel, error)
+	Count() (int64, error)
+	Delete(id int) error
+}
@@ -11,7 +11,29 @@ func addAdminRoutes(rg *gin.RouterGroup) {
 	router.GET("/clusters", adminv1.ListCluster)
 	router.POST("/clusters", adminv1.PostCluster)
 	router.PUT("/clusters/:id", adminv1.PutCluster)
+	router.PUT("/clusters/:id/options", adminv1.PutClusterOptions)
 	router.GET("/clusters/:id", adminv1.GetCluster)
 	router.DELETE("/clusters/:id", adminv1.DeleteCluster)
+	router.POST("/clusters/import", adminv1.ImportCluster)
+	router.POST("/clusters/reencrypt", adminv1.ReencryptClusters)
 	router.POST("/testConnectclusters/", adminv1.TestConnectCluster)
+
+	router.GET("/clients", adminv1.ListClients)
+	router.POST("/clients/:cluster/refresh", adminv1.RefreshClient)
+	router.DELETE("/clients/:cluster", adminv1.DeleteClient)
+
+	router.GET("/rules", adminv1.ListRules)
+	router.POST("/rules", adminv1.PostRule)
+	router.GET("/rules/:id", adminv1.GetRule)
+	router.PUT("/rules/:id", adminv1.PutRule)
+	router.DELETE("/rules/:id", adminv1.DeleteRule)
+
+	router.GET("/audit", adminv1.ListAudit)
+
+	router.GET("/callers", adminv1.ListCallers)
+	router.POST("/callers", adminv1.PostCaller)
+	router.GET("/callers/:id", adminv1.GetCaller)
+	router.PUT("/callers/:id", adminv1.PutCaller)
+	router.DELETE("/callers/:id", adminv1.DeleteCaller)
+	router.POST("/callers/:id/rotate", adminv1.RotateCaller)
 }
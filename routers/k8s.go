@@ -10,6 +10,8 @@ func addK8sRoutes(rg *gin.RouterGroup) {
 
 	router.GET("/:cluster/pods", k8sv1.GetPods)
 	router.GET("/:cluster/watch/pods", k8sv1.WatchPods)
+	router.POST("/:cluster/wstokens", k8sv1.PostWSToken)
+	router.POST("/:cluster/apply", k8sv1.PostApply)
 	router.GET("/:cluster/pods/:namespace/:podName/ssh", k8sv1.PodWebSSH)
 	router.GET("/:cluster/pods/:namespace/:podName/log", k8sv1.GetPodLog)
 	router.GET("/:cluster/pods/:namespace/:podName/:containerName/download_log", k8sv1.DownloadPodContainerLog)
@@ -28,6 +30,7 @@ func addK8sRoutes(rg *gin.RouterGroup) {
 
 	router.GET("/:cluster/services", k8sv1.GetServices)
 	router.GET("/:cluster/services/:namespace/:serviceName", k8sv1.GetService)
+	router.PATCH("/:cluster/services/:namespace/:serviceName", k8sv1.PatchService)
 
 	router.GET("/:cluster/jobs", k8sv1.GetJobs)
 	router.GET("/:cluster/jobs/:namespace/:jobName", k8sv1.GetJob)
@@ -37,6 +40,7 @@ func addK8sRoutes(rg *gin.RouterGroup) {
 	router.POST("/:cluster/cronjobs", k8sv1.PostCronJob)
 	router.GET("/:cluster/cronjobs/:namespace/:cronjobName", k8sv1.GetCronJob)
 	router.PUT("/:cluster/cronjobs/:namespace/:cronjobName", k8sv1.PutCronJob)
+	router.PATCH("/:cluster/cronjobs/:namespace/:cronjobName", k8sv1.PatchCronJob)
 	router.DELETE("/:cluster/cronjobs/:namespace/:cronjobName", k8sv1.DeleteCronJob)
 
 	router.GET("/:cluster/events", k8sv1.GetEvents)
@@ -48,17 +52,33 @@ func addK8sRoutes(rg *gin.RouterGroup) {
 	router.GET("/:cluster/horizontalpodautoscalers", k8sv1.GetHorizontalPodAutoScalerList)
 	router.GET("/:cluster/horizontalpodautoscalers/:namespace/:name", k8sv1.GetHorizontalPodAutoScaler)
 	router.PUT("/:cluster/horizontalpodautoscalers/:namespace/:name", k8sv1.PutHorizontalPodAutoScaler)
+	router.PATCH("/:cluster/horizontalpodautoscalers/:namespace/:name", k8sv1.PatchHorizontalPodAutoScaler)
 	router.DELETE("/:cluster/horizontalpodautoscalers/:namespace/:name", k8sv1.DeleteHorizontalPodAutoScaler)
 
 	router.POST("/:cluster/configmaps", k8sv1.PostConfigmap)
 	router.GET("/:cluster/configmaps", k8sv1.GetConfigmapList)
 	router.GET("/:cluster/configmaps/:namespace/:name", k8sv1.GetConfigmap)
 	router.PUT("/:cluster/configmaps/:namespace/:name", k8sv1.PutConfigmap)
+	router.PATCH("/:cluster/configmaps/:namespace/:name", k8sv1.PatchConfigmap)
 	router.DELETE("/:cluster/configmaps/:namespace/:name", k8sv1.DeleteConfigmap)
 
 	router.GET("/:cluster/crd/:group/:version/:resource", k8sv1.GetCRDs)
 	router.GET("/:cluster/crd/:group/:version/:resource/:namespace/:name", k8sv1.GetCRD)
 	router.POST("/:cluster/crd/:group/:version/:resource", k8sv1.PostCRD)
 	router.PUT("/:cluster/crd/:group/:version/:resource/:namespace/:name", k8sv1.PutCRD)
+	router.PATCH("/:cluster/crd/:group/:version/:resource/:namespace/:name", k8sv1.PatchCRD)
 	router.DELETE("/:cluster/crd/:group/:version/:resource/:namespace/:name", k8sv1.DeleteCRD)
+	router.GET("/:cluster/clustercrd/:group/:version/:resource", k8sv1.GetClusterCRDs)
+	router.GET("/:cluster/clustercrd/:group/:version/:resource/:name", k8sv1.GetCRD)
+	router.POST("/:cluster/clustercrd/:group/:version/:resource", k8sv1.PostClusterCRD)
+	router.PUT("/:cluster/clustercrd/:group/:version/:resource/:name", k8sv1.PutCRD)
+	router.PATCH("/:cluster/clustercrd/:group/:version/:resource/:name", k8sv1.PatchCRD)
+	router.DELETE("/:cluster/clustercrd/:group/:version/:resource/:name", k8sv1.DeleteCRD)
+
+	router.GET("/:cluster/resources/:kind", k8sv1.GetResources)
+	router.POST("/:cluster/resources/:kind", k8sv1.PostResource)
+	router.GET("/:cluster/resources/:kind/:name", k8sv1.GetResource)
+	router.PUT("/:cluster/resources/:kind/:name", k8sv1.PutResource)
+	router.PATCH("/:cluster/resources/:kind/:name", k8sv1.PatchResource)
+	router.DELETE("/:cluster/resources/:kind/:name", k8sv1.DeleteResource)
 }
@@ -17,6 +17,14 @@ func InitRouter() *gin.Engine {
 	r.NoRoute(app.HandleNotFound)
 	//Authentication
 	r.Use(app.Auth())
+	// Rate limits per caller and cluster
+	r.Use(app.RateLimit())
+	// yaml request bodies are converted to json for the handlers
+	r.Use(app.YAMLBody())
+	// Audit log, wraps authorization so that denied calls are recorded too
+	r.Use(app.Audit())
+	//Authorization
+	r.Use(app.Authorize())
 	// swagger config
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	docs.SwaggerInfo.BasePath = "/api/v1"
@@ -10,13 +10,3 @@ func MD5(str string) string {
 	s.Write([]byte(str))
 	return hex.EncodeToString(s.Sum(nil))
 }
-
-func CreateSign(appKey, appSecret string) string {
-	appKey = "value"
-	appSecret = "twsxwflergjiweqwsq"
-	encryptStr := appKey + "&ts=xxx"
-
-	// 自定义验证规则
-	sn := MD5(encryptStr + appSecret)
-	return sn
-}
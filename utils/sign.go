@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CreateSign returns the hex HMAC-SHA256 of a request, signed over
+//
+//	METHOD\nPATH\nSORTED_QUERY\nTIMESTAMP\nNONCE\nHEX(SHA256(BODY))
+//
+// query keys and the values of each key are sorted before encoding.
+func CreateSign(appSecret, method, path string, query url.Values, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	stringToSign := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		canonicalQuery(query),
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySign compares signature with the expected one in constant time
+func VerifySign(signature, expected string) bool {
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+func canonicalQuery(query url.Values) string {
+	sorted := make(url.Values, len(query))
+	for key, values := range query {
+		values = append([]string(nil), values...)
+		sort.Strings(values)
+		sorted[key] = values
+	}
+	// Encode sorts by key
+	return sorted.Encode()
+}